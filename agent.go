@@ -1,9 +1,12 @@
 package atoa
 
 import (
+	"crypto"
+	"crypto/ecdsa"
+	"encoding/json"
 	"errors"
 	"fmt"
-	"time"
+	"sort"
 
 	"github.com/golang-jwt/jwt/v5"
 )
@@ -15,20 +18,120 @@ type AgentCard struct {
 	Capabilities []string `json:"capabilities"`
 	Endpoints    []string `json:"endpoints"`
 	Verified     bool     `json:"verified"`
+
+	// Version is the agent's own version. The platform does not use it; it
+	// is published as the version in the agent's agent.json.
+	Version string `json:"version,omitempty"`
 }
 
 // Validate checks if the AgentCard has all required fields
 func (ac *AgentCard) Validate() error {
+	return ac.validationErrors().err()
+}
+
+// validationErrors collects every problem with the card fields
+func (ac *AgentCard) validationErrors() ValidationErrors {
+	var errs ValidationErrors
 	if ac.AgentID == "" {
-		return errors.New("agent_id is required")
+		errs.add("agent_id", "is required")
 	}
 	if ac.OrgID == "" {
-		return errors.New("org_id is required")
+		errs.add("org_id", "is required")
 	}
 	if len(ac.Capabilities) == 0 {
-		return errors.New("at least one capability is required")
+		errs.add("capabilities", "must contain at least one capability")
 	}
-	return nil
+	return errs
+}
+
+// Merge returns a copy of the card updated with the non-empty fields of
+// update. Capabilities and Endpoints are unioned, keeping the card's order
+// and appending new entries without duplicates. Verified is kept from the
+// card because only the platform sets it.
+func (ac *AgentCard) Merge(update AgentCard) AgentCard {
+	merged := AgentCard{
+		AgentID:      ac.AgentID,
+		OrgID:        ac.OrgID,
+		Capabilities: unionStrings(ac.Capabilities, update.Capabilities),
+		Endpoints:    unionStrings(ac.Endpoints, update.Endpoints),
+		Verified:     ac.Verified,
+		Version:      ac.Version,
+	}
+	if update.AgentID != "" {
+		merged.AgentID = update.AgentID
+	}
+	if update.OrgID != "" {
+		merged.OrgID = update.OrgID
+	}
+	if update.Version != "" {
+		merged.Version = update.Version
+	}
+	return merged
+}
+
+// unionStrings returns the distinct values of a followed by those of b that
+// are not in a, or nil if both are empty
+func unionStrings(a, b []string) []string {
+	var union []string
+	seen := make(map[string]bool, len(a)+len(b))
+	for _, values := range [][]string{a, b} {
+		for _, value := range values {
+			if !seen[value] {
+				seen[value] = true
+				union = append(union, value)
+			}
+		}
+	}
+	return union
+}
+
+// Sign produces a detached signature over the card's canonical form, made
+// with the organization's private key the same way as SignChallenge
+func (ac *AgentCard) Sign(privateKey *ecdsa.PrivateKey) (string, error) {
+	canonical, err := ac.canonicalBytes()
+	if err != nil {
+		return "", err
+	}
+	signature, err := SignChallenge(string(canonical), privateKey)
+	if err != nil {
+		return "", fmt.Errorf("failed to sign agent card: %w", err)
+	}
+	return signature, nil
+}
+
+// VerifyAgentCard verifies a signature made by AgentCard.Sign against the
+// organization's PEM-encoded public key
+func VerifyAgentCard(ac AgentCard, sig, orgPublicKeyPEM string) (bool, error) {
+	canonical, err := ac.canonicalBytes()
+	if err != nil {
+		return false, err
+	}
+	return VerifySignature(string(canonical), sig, orgPublicKeyPEM)
+}
+
+// canonicalBytes returns the deterministic encoding of the card that is
+// signed: a JSON object with sorted keys and sorted capability and endpoint
+// lists. Verified is left out because the platform, not the org, sets it.
+func (ac *AgentCard) canonicalBytes() ([]byte, error) {
+	canonical := map[string]interface{}{
+		"agent_id":     ac.AgentID,
+		"org_id":       ac.OrgID,
+		"capabilities": sortedCopy(ac.Capabilities),
+		"endpoints":    sortedCopy(ac.Endpoints),
+	}
+	// encoding/json writes map keys in sorted order
+	data, err := json.Marshal(canonical)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode agent card: %w", err)
+	}
+	return data, nil
+}
+
+// sortedCopy returns a sorted copy of values, never nil
+func sortedCopy(values []string) []string {
+	sorted := append([]string{}, values...)
+	sort.Strings(sorted)
+	return sorted
 }
 
 // AgentToken represents the JWT token issued to an agent
@@ -61,37 +164,59 @@ func (at *AgentToken) Validate() error {
 	}
 
 	// Check if token is expired
-	if time.Now().Unix() > at.Exp {
+	if timeNow().Unix() > at.Exp {
 		return errors.New("token is expired")
 	}
 
 	return nil
 }
 
-// ParseAgentToken parses a JWT token string into an AgentToken
-func ParseAgentToken(tokenString string) (*AgentToken, error) {
-	// Parse the JWT token
-	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
-		// Validate the signing method
-		if _, ok := token.Method.(*jwt.SigningMethodECDSA); !ok {
-			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
-		}
-		// TODO: Get the public key from the token header or a trusted source
-		// For now, return nil as we're just parsing
-		return nil, nil
-	}, jwt.WithExpirationRequired(), jwt.WithIssuedAt())
+// ParseAgentTokenVerified verifies the ES256, ES384, ES512 or RS256 signature
+// of a JWT token with the given public key and parses it into an AgentToken
+func ParseAgentTokenVerified(tokenString string, publicKey crypto.PublicKey) (*AgentToken, error) {
+	if publicKey == nil {
+		return nil, errors.New("public key is required")
+	}
+
+	token, err := jwt.Parse(tokenString, publicKeyFunc(publicKey), DefaultVerifyOptions().parserOptions()...)
 
 	if err != nil {
 		return nil, fmt.Errorf("failed to parse JWT: %w", err)
 	}
 
-	// Extract claims
 	claims, ok := token.Claims.(jwt.MapClaims)
 	if !ok {
 		return nil, errors.New("invalid token claims")
 	}
 
-	// Convert claims to AgentToken
+	return agentTokenFromClaims(claims)
+}
+
+// ParseAgentTokenUnverified parses a JWT token string into an AgentToken
+// WITHOUT verifying its signature. Anyone can forge a token that passes this
+// function, so it must never be used as a security boundary; use
+// ParseAgentTokenVerified for that.
+func ParseAgentTokenUnverified(tokenString string) (*AgentToken, error) {
+	claims := jwt.MapClaims{}
+	if _, _, err := jwt.NewParser().ParseUnverified(tokenString, claims); err != nil {
+		return nil, fmt.Errorf("failed to parse JWT: %w", err)
+	}
+
+	return agentTokenFromClaims(claims)
+}
+
+// ParseAgentToken parses a JWT token string into an AgentToken without
+// verifying its signature.
+//
+// Deprecated: the name hides that no verification happens. Use
+// ParseAgentTokenVerified, or ParseAgentTokenUnverified when inspecting a
+// token that is trusted through other means.
+func ParseAgentToken(tokenString string) (*AgentToken, error) {
+	return ParseAgentTokenUnverified(tokenString)
+}
+
+// agentTokenFromClaims converts JWT claims to an AgentToken and validates it
+func agentTokenFromClaims(claims jwt.MapClaims) (*AgentToken, error) {
 	agentToken := &AgentToken{
 		AgentID:      getStringClaim(claims, "agent_id"),
 		OrgID:        getStringClaim(claims, "org_id"),
@@ -99,7 +224,7 @@ func ParseAgentToken(tokenString string) (*AgentToken, error) {
 		Capabilities: getStringSliceClaim(claims, "capabilities"),
 		Exp:          int64(getFloatClaim(claims, "exp")),
 		Iss:          getStringClaim(claims, "iss"),
-		Aud:          getStringClaim(claims, "aud"),
+		Aud:          getAudienceClaim(claims),
 	}
 
 	// Validate the token structure
@@ -132,6 +257,18 @@ func getFloatClaim(claims jwt.MapClaims, key string) float64 {
 	return 0
 }
 
+// getAudienceClaim returns the first audience, which may be encoded as either
+// a single string or an array of strings
+func getAudienceClaim(claims jwt.MapClaims) string {
+	if aud := getStringClaim(claims, "aud"); aud != "" {
+		return aud
+	}
+	if aud := getStringSliceClaim(claims, "aud"); len(aud) > 0 {
+		return aud[0]
+	}
+	return ""
+}
+
 func getStringSliceClaim(claims jwt.MapClaims, key string) []string {
 	if val, ok := claims[key].([]interface{}); ok {
 		result := make([]string, len(val))
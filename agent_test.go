@@ -1,9 +1,18 @@
 package atoa
 
 import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
 	"net/http"
 	"net/http/httptest"
+	"reflect"
+	"sync"
 	"testing"
 	"time"
 )
@@ -60,6 +69,144 @@ func TestAgentCard_Validate(t *testing.T) {
 	}
 }
 
+func TestAgentClient_UpdateAgentCardDuringRefresh(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"token": "refreshed-token"}`))
+	}))
+	defer ts.Close()
+
+	client := NewAgentClient(ts.URL)
+	client.OrgToken = "org-token"
+	client.AgentCard = AgentCard{AgentID: "agent-1", OrgID: "test-org", Capabilities: []string{"text"}}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			if _, err := client.RefreshToken(context.Background()); err != nil {
+				t.Errorf("RefreshToken() error = %v", err)
+			}
+		}()
+		go func(i int) {
+			defer wg.Done()
+			capability := fmt.Sprintf("capability-%d", i)
+			if err := client.UpdateAgentCard(context.Background(), AgentCard{Capabilities: []string{capability}}); err != nil {
+				t.Errorf("UpdateAgentCard() error = %v", err)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	// Serialized updates do not lose each other's capabilities
+	if got := len(client.Card().Capabilities); got != 11 {
+		t.Errorf("len(Capabilities) = %d, want 11", got)
+	}
+}
+
+func TestAgentCard_Merge(t *testing.T) {
+	base := AgentCard{
+		AgentID:      "agent-1",
+		OrgID:        "test-org",
+		Capabilities: []string{"text", "file"},
+		Endpoints:    []string{"https://a.example"},
+		Verified:     true,
+	}
+
+	tests := []struct {
+		name   string
+		update AgentCard
+		want   AgentCard
+	}{
+		{
+			name:   "empty update keeps the card",
+			update: AgentCard{},
+			want:   base,
+		},
+		{
+			name:   "slices are unioned without duplicates",
+			update: AgentCard{Capabilities: []string{"file", "image", "image"}, Endpoints: []string{"https://b.example"}},
+			want: AgentCard{
+				AgentID:      "agent-1",
+				OrgID:        "test-org",
+				Capabilities: []string{"text", "file", "image"},
+				Endpoints:    []string{"https://a.example", "https://b.example"},
+				Verified:     true,
+			},
+		},
+		{
+			name:   "non-empty scalars override, verified is kept",
+			update: AgentCard{OrgID: "other-org", Verified: false},
+			want: AgentCard{
+				AgentID:      "agent-1",
+				OrgID:        "other-org",
+				Capabilities: []string{"text", "file"},
+				Endpoints:    []string{"https://a.example"},
+				Verified:     true,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := base.Merge(tt.update); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Merge() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+
+	// The receiver is not modified
+	if len(base.Capabilities) != 2 {
+		t.Errorf("base.Capabilities = %v, want unchanged", base.Capabilities)
+	}
+}
+
+func TestAgentClient_UpdateAgentCard(t *testing.T) {
+	var received AgentCard
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPut || r.URL.Path != "/agents/agent-1" {
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+		}
+		if err := json.NewDecoder(r.Body).Decode(&received); err != nil {
+			t.Errorf("failed to decode card: %v", err)
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer ts.Close()
+
+	client := NewAgentClient(ts.URL)
+	client.SetToken("valid-token")
+	client.AgentCard = AgentCard{AgentID: "agent-1", OrgID: "test-org", Capabilities: []string{"text"}}
+
+	if err := client.UpdateAgentCard(context.Background(), AgentCard{Capabilities: []string{"file"}}); err != nil {
+		t.Fatalf("UpdateAgentCard() error = %v", err)
+	}
+	want := []string{"text", "file"}
+	if !reflect.DeepEqual(received.Capabilities, want) {
+		t.Errorf("sent Capabilities = %v, want %v", received.Capabilities, want)
+	}
+	if !reflect.DeepEqual(client.AgentCard.Capabilities, want) {
+		t.Errorf("client.AgentCard.Capabilities = %v, want %v", client.AgentCard.Capabilities, want)
+	}
+
+	// Another agent's ID is rejected instead of retargeting the update
+	var idErrs ValidationErrors
+	if err := client.UpdateAgentCard(context.Background(), AgentCard{AgentID: "agent-2"}); !errors.As(err, &idErrs) || idErrs[0].Field != "agent_id" {
+		t.Errorf("UpdateAgentCard() with another agent_id error = %v, want agent_id ValidationErrors", err)
+	}
+	if got := client.Card().AgentID; got != "agent-1" {
+		t.Errorf("Card().AgentID = %v, want %v", got, "agent-1")
+	}
+
+	// An invalid merged card is rejected before sending
+	client.AgentCard = AgentCard{}
+	var errs ValidationErrors
+	if err := client.UpdateAgentCard(context.Background(), AgentCard{AgentID: "agent-2"}); !errors.As(err, &errs) {
+		t.Errorf("UpdateAgentCard() error = %v, want ValidationErrors", err)
+	}
+}
+
 func TestAgentClient_RegisterAgent(t *testing.T) {
 	tests := []struct {
 		name      string
@@ -150,6 +297,49 @@ func TestAgentClient_RegisterAgent(t *testing.T) {
 	}
 }
 
+func TestAgentClient_RegisterAgentWithStoredOrgToken(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			OrgToken string `json:"org_token"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Errorf("failed to decode request: %v", err)
+		}
+		if req.OrgToken != "stored-org-token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{"token": "agent-token"}`))
+	}))
+	defer ts.Close()
+
+	card := &AgentCard{
+		AgentID:      "test-agent",
+		OrgID:        "test-org",
+		Capabilities: []string{"text"},
+	}
+
+	client := NewAgentClient(ts.URL)
+	if _, err := client.RegisterAgentWithStoredOrgToken(card); err == nil {
+		t.Error("RegisterAgentWithStoredOrgToken() without org token error = nil, want error")
+	}
+
+	client.OrgToken = "stored-org-token"
+	token, err := client.RegisterAgentWithStoredOrgToken(card)
+	if err != nil {
+		t.Fatalf("RegisterAgentWithStoredOrgToken() error = %v", err)
+	}
+	if token != "agent-token" {
+		t.Errorf("RegisterAgentWithStoredOrgToken() token = %v, want %v", token, "agent-token")
+	}
+	if client.Token() != "agent-token" {
+		t.Errorf("client.Token() = %v, want %v", client.Token(), "agent-token")
+	}
+}
+
 func TestAgentClient_JoinSession(t *testing.T) {
 	tests := []struct {
 		name       string
@@ -276,3 +466,310 @@ func TestAgentToken_Validate(t *testing.T) {
 		})
 	}
 }
+
+func TestAgentToken_Validate_Clock(t *testing.T) {
+	now := time.Date(2024, 3, 20, 12, 0, 0, 0, time.UTC)
+	defer SetTimeFunc(func() time.Time { return now })()
+
+	token := &AgentToken{
+		AgentID: "test-agent",
+		OrgID:   "test-org",
+		Exp:     now.Add(time.Second).Unix(),
+		Iss:     "atoa.platform",
+		Aud:     "atoa.agent",
+	}
+	if err := token.Validate(); err != nil {
+		t.Errorf("Validate() one second before expiry error = %v", err)
+	}
+
+	now = now.Add(2 * time.Second)
+	if err := token.Validate(); err == nil {
+		t.Error("Validate() one second after expiry error = nil, want error")
+	}
+}
+
+func TestParseAgentTokenVerified(t *testing.T) {
+	privateKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate private key: %v", err)
+	}
+	otherKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate private key: %v", err)
+	}
+
+	orgToken, err := IssueOrgToken("test-org", true, privateKey)
+	if err != nil {
+		t.Fatalf("failed to issue org token: %v", err)
+	}
+	card := &AgentCard{
+		AgentID:      "test-agent",
+		OrgID:        "test-org",
+		Capabilities: []string{"text"},
+	}
+	token, err := IssueAgentToken(card, orgToken, privateKey)
+	if err != nil {
+		t.Fatalf("failed to issue agent token: %v", err)
+	}
+
+	tests := []struct {
+		name      string
+		publicKey *ecdsa.PublicKey
+		wantErr   bool
+	}{
+		{
+			name:      "matching key",
+			publicKey: &privateKey.PublicKey,
+			wantErr:   false,
+		},
+		{
+			name:      "wrong key",
+			publicKey: &otherKey.PublicKey,
+			wantErr:   true,
+		},
+		{
+			name:      "nil key",
+			publicKey: nil,
+			wantErr:   true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			agentToken, err := ParseAgentTokenVerified(token, tt.publicKey)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ParseAgentTokenVerified() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !tt.wantErr {
+				if agentToken.AgentID != "test-agent" {
+					t.Errorf("AgentID = %v, want %v", agentToken.AgentID, "test-agent")
+				}
+				if agentToken.Aud != AgentTokenAudience {
+					t.Errorf("Aud = %v, want %v", agentToken.Aud, AgentTokenAudience)
+				}
+			}
+		})
+	}
+
+	// The unverified variant accepts the token regardless of key
+	if _, err := ParseAgentTokenUnverified(token); err != nil {
+		t.Errorf("ParseAgentTokenUnverified() error = %v", err)
+	}
+}
+
+func TestAgentClient_Close(t *testing.T) {
+	started := make(chan struct{})
+	release := make(chan struct{})
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	}))
+	defer ts.Close()
+	defer close(release)
+
+	client := NewAgentClient(ts.URL)
+	client.SetToken("valid-token")
+
+	errc := make(chan error, 1)
+	go func() {
+		_, err := client.ListOffers(context.Background())
+		errc <- err
+	}()
+
+	<-started
+	if err := client.Close(); err != nil {
+		t.Errorf("Close() error = %v", err)
+	}
+
+	// The in-flight request is aborted
+	if err := <-errc; !errors.Is(err, context.Canceled) {
+		t.Errorf("in-flight ListOffers() error = %v, want %v", err, context.Canceled)
+	}
+
+	// New requests are rejected without reaching the server
+	if _, err := client.ListOffers(context.Background()); !errors.Is(err, ErrClientClosed) {
+		t.Errorf("ListOffers() after Close error = %v, want %v", err, ErrClientClosed)
+	}
+	if err := client.JoinSession("session-1", "valid-token"); !errors.Is(err, ErrClientClosed) {
+		t.Errorf("JoinSession() after Close error = %v, want %v", err, ErrClientClosed)
+	}
+
+	// Closing again is a no-op
+	if err := client.Close(); err != nil {
+		t.Errorf("second Close() error = %v", err)
+	}
+}
+
+func TestAgentCard_SignRoundtrip(t *testing.T) {
+	orgKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate private key: %v", err)
+	}
+	publicKeyPEM, err := MarshalPublicKeyPEM(&orgKey.PublicKey)
+	if err != nil {
+		t.Fatalf("failed to marshal public key: %v", err)
+	}
+
+	card := AgentCard{
+		AgentID:      "agent-1",
+		OrgID:        "test-org",
+		Capabilities: []string{"text", "file"},
+		Endpoints:    []string{"https://agent.test.org"},
+	}
+	sig, err := card.Sign(orgKey)
+	if err != nil {
+		t.Fatalf("Sign() error = %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		modify func(ac *AgentCard)
+		want   bool
+	}{
+		{name: "unchanged", modify: func(ac *AgentCard) {}, want: true},
+		{name: "capabilities reordered", modify: func(ac *AgentCard) { ac.Capabilities = []string{"file", "text"} }, want: true},
+		{name: "verified set by platform", modify: func(ac *AgentCard) { ac.Verified = true }, want: true},
+		{name: "agent id changed", modify: func(ac *AgentCard) { ac.AgentID = "agent-2" }, want: false},
+		{name: "capability added", modify: func(ac *AgentCard) { ac.Capabilities = append(ac.Capabilities, "data") }, want: false},
+		{name: "endpoint changed", modify: func(ac *AgentCard) { ac.Endpoints = []string{"https://evil.example"} }, want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			received := card
+			received.Capabilities = append([]string(nil), card.Capabilities...)
+			tt.modify(&received)
+
+			got, err := VerifyAgentCard(received, sig, publicKeyPEM)
+			if err != nil {
+				t.Fatalf("VerifyAgentCard() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("VerifyAgentCard() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	// A different org key does not verify the signature
+	got, err := VerifyAgentCard(card, sig, generateTestPublicKey(t))
+	if err != nil {
+		t.Fatalf("VerifyAgentCard() error = %v", err)
+	}
+	if got {
+		t.Error("VerifyAgentCard() with another org's key = true, want false")
+	}
+}
+
+func TestAgentClient_doJSON(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if got := r.Header.Get("Authorization"); got != "Bearer valid-token" {
+			t.Errorf("Authorization = %q, want %q", got, "Bearer valid-token")
+		}
+		switch r.URL.Path {
+		case "/echo":
+			if got := r.Header.Get("Content-Type"); got != "application/json" {
+				t.Errorf("Content-Type = %q, want %q", got, "application/json")
+			}
+			w.WriteHeader(http.StatusCreated)
+			io.Copy(w, r.Body)
+		case "/empty":
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			http.Error(w, "no such endpoint", http.StatusNotFound)
+		}
+	}))
+	defer ts.Close()
+
+	client := &AgentClient{BaseURL: ts.URL, HTTP: &http.Client{}}
+	client.SetToken("valid-token")
+
+	var out struct {
+		Value string `json:"value"`
+	}
+	if err := client.doJSON(context.Background(), http.MethodPost, "/echo", map[string]string{"value": "x"}, &out); err != nil {
+		t.Fatalf("doJSON() error = %v", err)
+	}
+	if out.Value != "x" {
+		t.Errorf("out.Value = %q, want %q", out.Value, "x")
+	}
+
+	if err := client.doJSON(context.Background(), http.MethodDelete, "/empty", nil, &out); err != nil {
+		t.Errorf("doJSON() with 204 error = %v", err)
+	}
+
+	err := client.doJSON(context.Background(), http.MethodGet, "/missing", nil, &out)
+	if !hasStatus(err, http.StatusNotFound) {
+		t.Errorf("doJSON() error = %v, want APIError with status 404", err)
+	}
+}
+
+func TestJoinURL(t *testing.T) {
+	tests := []struct {
+		name    string
+		baseURL string
+		prefix  string
+		path    string
+		want    string
+	}{
+		{name: "no prefix", baseURL: "http://platform", prefix: "", path: "/offers", want: "http://platform/offers"},
+		{name: "prefix", baseURL: "http://platform", prefix: "/v1", path: "/offers", want: "http://platform/v1/offers"},
+		{name: "extra slashes", baseURL: "http://platform/", prefix: "/v1/", path: "/offers", want: "http://platform/v1/offers"},
+		{name: "prefix without slashes", baseURL: "http://platform", prefix: "v2", path: "offers", want: "http://platform/v2/offers"},
+		{name: "base with path", baseURL: "http://platform/api/", prefix: "v1", path: "/sessions?status=active", want: "http://platform/api/v1/sessions?status=active"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := joinURL(tt.baseURL, tt.prefix, tt.path); got != tt.want {
+				t.Errorf("joinURL() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAPIPrefix(t *testing.T) {
+	var paths []string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		paths = append(paths, r.URL.Path)
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/v1/orgs/register":
+			w.Write([]byte(`{"challenge": "test-challenge"}`))
+		default:
+			w.Write([]byte(`[]`))
+		}
+	}))
+	defer ts.Close()
+
+	orgClient := NewOrgClient(ts.URL + "/")
+	orgClient.APIPrefix = "/v1/"
+	_, err := orgClient.RegisterOrg(&OrgCard{
+		OrgID:     "test-org",
+		Name:      "Test Org",
+		Domain:    "test.org",
+		PublicKey: generateTestPublicKey(t),
+	})
+	if err != nil {
+		t.Fatalf("RegisterOrg() error = %v", err)
+	}
+
+	agentClient := NewAgentClient(ts.URL)
+	agentClient.APIPrefix = "/v1"
+	if _, err := agentClient.ListOffers(context.Background()); err != nil {
+		t.Fatalf("ListOffers() error = %v", err)
+	}
+
+	want := []string{"/v1/orgs/register", "/v1/offers"}
+	if len(paths) != len(want) {
+		t.Fatalf("paths = %v, want %v", paths, want)
+	}
+	for i := range want {
+		if paths[i] != want[i] {
+			t.Errorf("paths[%d] = %v, want %v", i, paths[i], want[i])
+		}
+	}
+}
@@ -1,63 +1,138 @@
 package atoa
 
 import (
-	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
+	"net/url"
+	"strings"
+	"sync"
 )
 
+// ResponseDecoderFunc decodes a successful response body into v. It lets
+// callers adapt to deployments that wrap responses in custom envelopes.
+type ResponseDecoderFunc func(resp *http.Response, v interface{}) error
+
+// decodeResponse decodes resp into v with decoder, or as plain JSON when
+// decoder is nil
+func decodeResponse(decoder ResponseDecoderFunc, resp *http.Response, v interface{}) error {
+	if err := decompressResponse(resp); err != nil {
+		return err
+	}
+	if decoder != nil {
+		return decoder(resp, v)
+	}
+	return json.NewDecoder(resp.Body).Decode(v)
+}
+
+// joinURL combines a base URL, an optional API prefix and a path with exactly
+// one slash between each part
+func joinURL(baseURL, prefix, path string) string {
+	u := strings.TrimRight(baseURL, "/")
+	if prefix = strings.Trim(prefix, "/"); prefix != "" {
+		u += "/" + prefix
+	}
+	return u + "/" + strings.TrimLeft(path, "/")
+}
+
+// Version is the version of this SDK
+const Version = "0.1.0"
+
+// DefaultUserAgent is sent with every request unless the client sets UserAgent
+const DefaultUserAgent = "atoa-go/" + Version
+
 // OrgClient handles organization registration and authentication
 type OrgClient struct {
 	BaseURL string
 	HTTP    *http.Client
+
+	// APIPrefix is inserted between BaseURL and every request path, e.g.
+	// "/v1" to route all calls under that API version
+	APIPrefix string
+
+	// ResponseDecoder replaces the default JSON decoding of responses
+	ResponseDecoder ResponseDecoderFunc
+
+	// UserAgent is sent in the User-Agent header. Empty means
+	// DefaultUserAgent.
+	UserAgent string
+
+	// Header holds extra headers sent with every request
+	Header http.Header
+
+	// Retry, when set, retries idempotent requests that fail transiently
+	Retry *RetryPolicy
 }
 
-// NewOrgClient creates a new OrgClient with the given base URL
-func NewOrgClient(baseURL string) *OrgClient {
+// NewOrgClient creates a new OrgClient with the given base URL and options
+func NewOrgClient(baseURL string, opts ...ClientOption) *OrgClient {
+	o := applyOptions(opts)
 	return &OrgClient{
-		BaseURL: baseURL,
-		HTTP:    &http.Client{},
+		BaseURL:   baseURL,
+		HTTP:      o.httpClient(),
+		UserAgent: o.userAgent,
+		Header:    o.header,
+		Retry:     o.retry,
 	}
 }
 
-// RegisterOrg registers a new organization and returns a challenge
-func (c *OrgClient) RegisterOrg(card *OrgCard) (string, error) {
-	if err := card.Validate(); err != nil {
-		return "", fmt.Errorf("invalid org card: %w", err)
-	}
+// endpoint returns the URL of an API path
+func (c *OrgClient) endpoint(path string) string {
+	return joinURL(c.BaseURL, c.APIPrefix, path)
+}
 
-	payload, err := json.Marshal(card)
-	if err != nil {
-		return "", fmt.Errorf("failed to marshal org card: %w", err)
-	}
+// setHeaders applies UserAgent and Header to a new request
+func (c *OrgClient) setHeaders(req *http.Request) {
+	setRequestHeaders(req, c.UserAgent, c.Header)
+}
 
-	resp, err := c.HTTP.Post(
-		fmt.Sprintf("%s/orgs/register", c.BaseURL),
-		"application/json",
-		bytes.NewBuffer(payload),
-	)
-	if err != nil {
-		return "", fmt.Errorf("failed to register org: %w", err)
-	}
-	defer resp.Body.Close()
+// RegisterOrg registers a new organization and returns the challenge to sign.
+// Sign challenge.String() with SignChallenge and pass both to
+// RequestTokenForChallenge, or pass the string to RequestToken.
+func (c *OrgClient) RegisterOrg(card *OrgCard) (Challenge, error) {
+	return c.RegisterOrgContext(context.Background(), card)
+}
 
-	if resp.StatusCode != http.StatusOK {
-		return "", fmt.Errorf("registration failed with status %d", resp.StatusCode)
+// RegisterOrgContext is like RegisterOrg but honors ctx cancellation and deadlines
+func (c *OrgClient) RegisterOrgContext(ctx context.Context, card *OrgCard) (Challenge, error) {
+	if err := card.Validate(); err != nil {
+		return Challenge{}, fmt.Errorf("invalid org card: %w", err)
 	}
 
-	var result struct {
-		Challenge string `json:"challenge"`
-	}
-	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
-		return "", fmt.Errorf("failed to decode response: %w", err)
+	// Servers send either the structured challenge or only a bare string in
+	// its "challenge" field
+	var challenge Challenge
+	if err := c.doJSON(ctx, http.MethodPost, "/orgs/register", card, &challenge); err != nil {
+		return Challenge{}, fmt.Errorf("registration failed: %w", err)
 	}
+	return challenge, nil
+}
 
-	return result.Challenge, nil
+// RequestTokenForChallenge requests a JWT token with a signature over
+// challenge.String(). It fails with ErrChallengeExpired, without a request,
+// once the challenge has expired.
+func (c *OrgClient) RequestTokenForChallenge(orgID string, challenge Challenge, signature string) (string, error) {
+	return c.RequestTokenForChallengeContext(context.Background(), orgID, challenge, signature)
 }
 
-// RequestToken requests a JWT token after signing the challenge
+// RequestTokenForChallengeContext is like RequestTokenForChallenge but honors
+// ctx cancellation and deadlines
+func (c *OrgClient) RequestTokenForChallengeContext(ctx context.Context, orgID string, challenge Challenge, signature string) (string, error) {
+	if challenge.Expired() {
+		return "", ErrChallengeExpired
+	}
+	return c.RequestTokenContext(ctx, orgID, challenge.String(), signature)
+}
+
+// RequestToken requests a JWT token with a signature over the challenge string
 func (c *OrgClient) RequestToken(orgID, challenge, signature string) (string, error) {
+	return c.RequestTokenContext(context.Background(), orgID, challenge, signature)
+}
+
+// RequestTokenContext is like RequestToken but honors ctx cancellation and deadlines
+func (c *OrgClient) RequestTokenContext(ctx context.Context, orgID, challenge, signature string) (string, error) {
 	payload := struct {
 		OrgID     string `json:"org_id"`
 		Challenge string `json:"challenge"`
@@ -68,30 +143,11 @@ func (c *OrgClient) RequestToken(orgID, challenge, signature string) (string, er
 		Signature: signature,
 	}
 
-	body, err := json.Marshal(payload)
-	if err != nil {
-		return "", fmt.Errorf("failed to marshal request: %w", err)
-	}
-
-	resp, err := c.HTTP.Post(
-		fmt.Sprintf("%s/orgs/token", c.BaseURL),
-		"application/json",
-		bytes.NewBuffer(body),
-	)
-	if err != nil {
-		return "", fmt.Errorf("failed to request token: %w", err)
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		return "", fmt.Errorf("token request failed with status %d", resp.StatusCode)
-	}
-
 	var result struct {
 		Token string `json:"token"`
 	}
-	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
-		return "", fmt.Errorf("failed to decode response: %w", err)
+	if err := c.doJSON(ctx, http.MethodPost, "/orgs/token", payload, &result); err != nil {
+		return "", fmt.Errorf("token request failed: %w", err)
 	}
 
 	return result.Token, nil
@@ -99,23 +155,155 @@ func (c *OrgClient) RequestToken(orgID, challenge, signature string) (string, er
 
 // AgentClient handles agent registration and authentication
 type AgentClient struct {
+	// AgentCard is the card the client registers and refreshes with. Once
+	// StartAutoRefresh is running or UpdateAgentCard may be called
+	// concurrently, read it with Card instead.
 	AgentCard AgentCard
 	OrgToken  string
-	Token     string
 	BaseURL   string
 	HTTP      *http.Client
+
+	// APIPrefix is inserted between BaseURL and every request path, e.g.
+	// "/v1" to route all calls under that API version
+	APIPrefix string
+
+	// ResponseDecoder replaces the default JSON decoding of responses
+	ResponseDecoder ResponseDecoderFunc
+
+	// RequireVerified makes SendMessage check the verified claim of Token
+	// before making the request. Callers who trust the server to enforce
+	// verification can leave it unset.
+	RequireVerified bool
+
+	// CheckRequirements makes CreateSession verify locally that the agent's
+	// capabilities and protocol version satisfy the offer's requirements
+	CheckRequirements bool
+
+	// SkipVerifiedCheck turns off the client-side check that makes ListOffers
+	// and CreateSession fail with ErrAgentNotVerified, without a request,
+	// when Token is not verified
+	SkipVerifiedCheck bool
+
+	// AllowCustomOfferTypes lets UpdateOffer publish offers whose type is not
+	// one of the predefined OfferType constants
+	AllowCustomOfferTypes bool
+
+	// AllowCustomMessageTypes lets SendMessage send messages whose type is
+	// not one of the predefined MessageType constants
+	AllowCustomMessageTypes bool
+
+	// MaxPayloadBytes limits the size of message payloads accepted by
+	// SendMessage. With RecipientKey set, the encrypted envelope must fit as
+	// well. Zero means DefaultMaxPayloadBytes.
+	MaxPayloadBytes int
+
+	// PayloadValidator, when set, makes SendMessage check payloads against
+	// the schema registered for their message type before sending
+	PayloadValidator *PayloadValidator
+
+	// CompressionThreshold makes offer and message requests gzip-compress
+	// bodies larger than this many bytes. Zero disables compression.
+	CompressionThreshold int
+
+	// OrderMessages makes SendMessage number messages per session; see
+	// A2AMessage.Seq
+	OrderMessages bool
+
+	// RecipientKey, when set, makes SendMessage encrypt payloads to the
+	// public key it returns for the message's ToAgentID; see EncryptPayload
+	RecipientKey RecipientKeyFunc
+
+	// OfferScorer ranks offers in RecommendOffers. Nil means
+	// DefaultScoreWeights.Scorer().
+	OfferScorer OfferScorer
+
+	// UserAgent is sent in the User-Agent header. Empty means
+	// DefaultUserAgent.
+	UserAgent string
+
+	// Header holds extra headers sent with every request
+	Header http.Header
+
+	// WildcardCapabilities makes requirement checks in CreateSession and
+	// RecommendOffers treat required capabilities as patterns; see
+	// WithWildcards
+	WildcardCapabilities bool
+
+	// VerifiedOffersOnly makes ListOffers drop offers from unverified orgs,
+	// as a client-side check on top of any server-side filtering
+	VerifiedOffersOnly bool
+
+	// Codec encodes messages sent by SendMessage and decodes receipts
+	// returned in its content type. Nil means JSONCodec.
+	Codec Codec
+
+	// Retry, when set, retries idempotent requests that fail transiently
+	Retry *RetryPolicy
+
+	offerCache offerCache
+
+	// refreshMu guards lastRefreshErr
+	refreshMu      sync.Mutex
+	lastRefreshErr error
+
+	// heartbeatMu guards lastHeartbeatErr
+	heartbeatMu      sync.Mutex
+	lastHeartbeatErr error
+
+	// cardMu guards AgentCard, which UpdateAgentCard may replace while a
+	// refresh is reading it. cardUpdateMu serializes UpdateAgentCard calls so
+	// that none of them is lost.
+	cardMu       sync.RWMutex
+	cardUpdateMu sync.Mutex
+
+	// tokenMu guards token, which RefreshToken may replace while requests
+	// are reading it
+	tokenMu sync.RWMutex
+	token   string
+
+	// seqMu guards seqs, the last sequence number sent per session
+	seqMu sync.Mutex
+	seqs  map[string]uint64
+
+	// closeMu guards closed and closeCtx
+	closeMu     sync.Mutex
+	closed      bool
+	closeCtx    context.Context
+	closeCancel context.CancelFunc
 }
 
-// NewAgentClient creates a new AgentClient with the given base URL
-func NewAgentClient(baseURL string) *AgentClient {
+// NewAgentClient creates a new AgentClient with the given base URL and options
+func NewAgentClient(baseURL string, opts ...ClientOption) *AgentClient {
+	o := applyOptions(opts)
 	return &AgentClient{
-		BaseURL: baseURL,
-		HTTP:    &http.Client{},
+		BaseURL:              baseURL,
+		HTTP:                 o.httpClient(),
+		CompressionThreshold: o.compressionThreshold,
+		UserAgent:            o.userAgent,
+		Header:               o.header,
+		Codec:                o.codec,
+		VerifiedOffersOnly:   o.verifiedOffersOnly,
+		Retry:                o.retry,
 	}
 }
 
+// endpoint returns the URL of an API path
+func (c *AgentClient) endpoint(path string) string {
+	return joinURL(c.BaseURL, c.APIPrefix, path)
+}
+
+// setHeaders applies UserAgent and Header to a new request
+func (c *AgentClient) setHeaders(req *http.Request) {
+	setRequestHeaders(req, c.UserAgent, c.Header)
+}
+
 // RegisterAgent registers a new agent and returns a JWT token
 func (c *AgentClient) RegisterAgent(card *AgentCard, orgToken string) (string, error) {
+	return c.RegisterAgentContext(context.Background(), card, orgToken)
+}
+
+// RegisterAgentContext is like RegisterAgent but honors ctx cancellation
+func (c *AgentClient) RegisterAgentContext(ctx context.Context, card *AgentCard, orgToken string) (string, error) {
 	if err := card.Validate(); err != nil {
 		return "", fmt.Errorf("invalid agent card: %w", err)
 	}
@@ -128,33 +316,123 @@ func (c *AgentClient) RegisterAgent(card *AgentCard, orgToken string) (string, e
 		OrgToken:  orgToken,
 	}
 
-	body, err := json.Marshal(payload)
-	if err != nil {
-		return "", fmt.Errorf("failed to marshal request: %w", err)
+	var result struct {
+		Token string `json:"token"`
 	}
+	if err := c.doJSON(ctx, http.MethodPost, "/agents/token", payload, &result); err != nil {
+		return "", fmt.Errorf("registration failed: %w", err)
+	}
+
+	return result.Token, nil
+}
 
-	resp, err := c.HTTP.Post(
-		fmt.Sprintf("%s/agents/token", c.BaseURL),
-		"application/json",
-		bytes.NewBuffer(body),
-	)
+// RegisterAgentWithStoredOrgToken registers a new agent using c.OrgToken and
+// stores the card and the returned token on the client
+func (c *AgentClient) RegisterAgentWithStoredOrgToken(card *AgentCard) (string, error) {
+	if c.OrgToken == "" {
+		return "", errors.New("org token is not set on the client")
+	}
+
+	token, err := c.RegisterAgent(card, c.OrgToken)
 	if err != nil {
-		return "", fmt.Errorf("failed to register agent: %w", err)
+		return "", err
 	}
-	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		return "", fmt.Errorf("registration failed with status %d", resp.StatusCode)
+	c.setCard(*card)
+	c.SetToken(token)
+	return token, nil
+}
+
+// Token returns the agent token used to authenticate requests
+func (c *AgentClient) Token() string {
+	c.tokenMu.RLock()
+	defer c.tokenMu.RUnlock()
+	return c.token
+}
+
+// SetToken replaces the agent token used to authenticate requests. It is safe
+// to call while other requests are in flight.
+func (c *AgentClient) SetToken(token string) {
+	c.tokenMu.Lock()
+	defer c.tokenMu.Unlock()
+	c.token = token
+}
+
+// verifiedPolicy says how checkVerified treats a token it cannot judge
+type verifiedPolicy int
+
+const (
+	// requireVerified fails for empty and undecodable tokens, for callers
+	// that asked for the check with RequireVerified
+	requireVerified verifiedPolicy = iota
+	// gateVerified lets empty and undecodable tokens through for the server
+	// to judge, sparing only requests the platform would reject with 403.
+	// SkipVerifiedCheck turns it off.
+	gateVerified
+)
+
+// checkVerified decodes the agent token and returns ErrAgentNotVerified if its
+// verified claim is false. Tokens that are empty or cannot be decoded are
+// handled according to policy. The signature is not checked: the client does
+// not hold the platform key, and the server remains the authority.
+func (c *AgentClient) checkVerified(policy verifiedPolicy) error {
+	token := c.Token()
+	if policy == gateVerified && (c.SkipVerifiedCheck || token == "") {
+		return nil
 	}
+	claims, err := PeekAgentClaims(token)
+	if err != nil {
+		if policy == gateVerified {
+			return nil
+		}
+		return fmt.Errorf("failed to decode agent token: %w", err)
+	}
+	if !claims.Verified {
+		return ErrAgentNotVerified
+	}
+	return nil
+}
 
-	var result struct {
-		Token string `json:"token"`
+// Card returns the client's AgentCard. It is safe to call while
+// UpdateAgentCard or a refresh is running.
+func (c *AgentClient) Card() AgentCard {
+	c.cardMu.RLock()
+	defer c.cardMu.RUnlock()
+	return c.AgentCard
+}
+
+// setCard replaces the client's AgentCard
+func (c *AgentClient) setCard(card AgentCard) {
+	c.cardMu.Lock()
+	defer c.cardMu.Unlock()
+	c.AgentCard = card
+}
+
+// UpdateAgentCard merges card into the client's AgentCard with
+// AgentCard.Merge, validates the result and PUTs it to /agents/{id}. The
+// client's card is replaced with the merged card once the platform accepts
+// it. A card naming a different AgentID is rejected rather than updating
+// another agent.
+func (c *AgentClient) UpdateAgentCard(ctx context.Context, card AgentCard) error {
+	c.cardUpdateMu.Lock()
+	defer c.cardUpdateMu.Unlock()
+
+	current := c.Card()
+	if card.AgentID != "" && current.AgentID != "" && card.AgentID != current.AgentID {
+		errs := ValidationErrors{{Field: "agent_id", Reason: fmt.Sprintf("cannot change from %q to %q", current.AgentID, card.AgentID)}}
+		return fmt.Errorf("invalid agent card: %w", errs)
 	}
-	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
-		return "", fmt.Errorf("failed to decode response: %w", err)
+
+	merged := current.Merge(card)
+	if err := merged.Validate(); err != nil {
+		return fmt.Errorf("invalid agent card: %w", err)
 	}
 
-	return result.Token, nil
+	if err := c.doJSON(ctx, http.MethodPut, "/agents/"+url.PathEscape(merged.AgentID), merged, nil); err != nil {
+		return err
+	}
+	c.setCard(merged)
+	return nil
 }
 
 // JoinSession attempts to join a session using the agent's token
@@ -167,24 +445,61 @@ func (c *AgentClient) JoinSession(sessionID, agentToken string) error {
 		Token:     agentToken,
 	}
 
-	body, err := json.Marshal(payload)
-	if err != nil {
-		return fmt.Errorf("failed to marshal request: %w", err)
+	if err := c.doJSON(context.Background(), http.MethodPost, "/sessions/join", payload, nil); err != nil {
+		return fmt.Errorf("join failed: %w", err)
 	}
 
-	resp, err := c.HTTP.Post(
-		fmt.Sprintf("%s/sessions/join", c.BaseURL),
-		"application/json",
-		bytes.NewBuffer(body),
-	)
-	if err != nil {
-		return fmt.Errorf("failed to join session: %w", err)
-	}
-	defer resp.Body.Close()
+	return nil
+}
 
-	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("join failed with status %d", resp.StatusCode)
+// Close releases the client's resources: it aborts in-flight requests, stops
+// any background loops, and closes idle HTTP connections. Requests started
+// after Close fail with ErrClientClosed. Close is safe to call multiple times
+// and concurrently with other methods.
+func (c *AgentClient) Close() error {
+	c.closeMu.Lock()
+	if c.closed {
+		c.closeMu.Unlock()
+		return nil
 	}
+	c.closed = true
+	if c.closeCancel != nil {
+		c.closeCancel()
+	}
+	c.closeMu.Unlock()
 
+	if c.HTTP != nil {
+		c.HTTP.CloseIdleConnections()
+	}
 	return nil
 }
+
+// lifetime returns a context that is canceled when the client is closed
+func (c *AgentClient) lifetime() context.Context {
+	c.closeMu.Lock()
+	defer c.closeMu.Unlock()
+	if c.closeCtx == nil {
+		c.closeCtx, c.closeCancel = context.WithCancel(context.Background())
+		if c.closed {
+			c.closeCancel()
+		}
+	}
+	return c.closeCtx
+}
+
+// beginRequest derives a request context from ctx that is also canceled when
+// the client is closed. The returned done function must be called once the
+// request has finished.
+func (c *AgentClient) beginRequest(ctx context.Context) (context.Context, func(), error) {
+	lifetime := c.lifetime()
+	if lifetime.Err() != nil {
+		return nil, nil, ErrClientClosed
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	stop := context.AfterFunc(lifetime, cancel)
+	return ctx, func() {
+		stop()
+		cancel()
+	}, nil
+}
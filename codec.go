@@ -0,0 +1,66 @@
+package atoa
+
+import (
+	"encoding/json"
+	"mime"
+	"net/http"
+)
+
+// Codec serializes A2A messages and their receipts on the wire
+type Codec interface {
+	Marshal(v interface{}) ([]byte, error)
+	Unmarshal(data []byte, v interface{}) error
+	// ContentType is the media type of the encoding, e.g. "application/json"
+	ContentType() string
+}
+
+// JSONCodec is the default Codec
+type JSONCodec struct{}
+
+// Marshal implements Codec
+func (JSONCodec) Marshal(v interface{}) ([]byte, error) {
+	return json.Marshal(v)
+}
+
+// Unmarshal implements Codec
+func (JSONCodec) Unmarshal(data []byte, v interface{}) error {
+	return json.Unmarshal(data, v)
+}
+
+// ContentType implements Codec
+func (JSONCodec) ContentType() string {
+	return "application/json"
+}
+
+// WithCodec makes an AgentClient encode messages with codec instead of JSON.
+// OrgClient ignores this option.
+func WithCodec(codec Codec) ClientOption {
+	return func(o *clientOptions) {
+		o.codec = codec
+	}
+}
+
+// codec returns the client's Codec, defaulting to JSON
+func (c *AgentClient) codec() Codec {
+	if c.Codec == nil {
+		return JSONCodec{}
+	}
+	return c.Codec
+}
+
+// acceptCodec asks for responses in the codec's encoding, with JSON as the
+// fallback for servers that only speak JSON
+func acceptCodec(req *http.Request, codec Codec) {
+	accept := codec.ContentType()
+	if accept != "application/json" {
+		accept += ", application/json;q=0.9"
+	}
+	req.Header.Set("Accept", accept)
+}
+
+// hasContentType reports whether resp declares the given media type,
+// ignoring parameters such as charset
+func hasContentType(resp *http.Response, contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	return err == nil && mediaType == contentType
+}
@@ -0,0 +1,93 @@
+package atoa
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/unscalers/atoamarket_poc/atoa_go/msgpack"
+)
+
+func TestSendMessage_Codec(t *testing.T) {
+	tests := []struct {
+		name         string
+		replyMsgpack bool
+	}{
+		{name: "server answers in msgpack", replyMsgpack: true},
+		{name: "server answers in JSON", replyMsgpack: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var received A2AMessage
+			var gotContentType, gotAccept string
+			ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				gotContentType = r.Header.Get("Content-Type")
+				gotAccept = r.Header.Get("Accept")
+				body, _ := io.ReadAll(r.Body)
+				if err := (msgpack.Codec{}).Unmarshal(body, &received); err != nil {
+					t.Errorf("failed to decode message: %v", err)
+				}
+
+				receipt := MessageReceipt{MessageID: "msg-1", Status: "accepted"}
+				if tt.replyMsgpack {
+					data, _ := msgpack.Codec{}.Marshal(receipt)
+					w.Header().Set("Content-Type", msgpack.ContentType)
+					w.Write(data)
+					return
+				}
+				w.Header().Set("Content-Type", "application/json")
+				json.NewEncoder(w).Encode(receipt)
+			}))
+			defer ts.Close()
+
+			client := NewAgentClient(ts.URL, WithCodec(msgpack.Codec{}))
+			client.SetToken("valid-token")
+
+			receipt, err := client.SendMessageWithReceipt(context.Background(), A2AMessage{
+				SessionID:   "session-123",
+				FromAgentID: "agent-1",
+				ToAgentID:   "agent-2",
+				Type:        MessageTypeText,
+				Payload:     json.RawMessage(`{"content":"Hello"}`),
+				Timestamp:   time.Now(),
+			})
+			if err != nil {
+				t.Fatalf("SendMessageWithReceipt() error = %v", err)
+			}
+
+			if gotContentType != msgpack.ContentType {
+				t.Errorf("Content-Type = %q, want %q", gotContentType, msgpack.ContentType)
+			}
+			if want := msgpack.ContentType + ", application/json;q=0.9"; gotAccept != want {
+				t.Errorf("Accept = %q, want %q", gotAccept, want)
+			}
+			if received.SessionID != "session-123" || string(received.Payload) != `{"content":"Hello"}` {
+				t.Errorf("server received %+v", received)
+			}
+			if receipt.MessageID != "msg-1" {
+				t.Errorf("MessageID = %q, want %q", receipt.MessageID, "msg-1")
+			}
+		})
+	}
+}
+
+func TestJSONCodec(t *testing.T) {
+	var codec Codec = JSONCodec{}
+	if codec.ContentType() != "application/json" {
+		t.Errorf("ContentType() = %q, want application/json", codec.ContentType())
+	}
+
+	data, err := codec.Marshal(MessageReceipt{MessageID: "msg-1"})
+	if err != nil {
+		t.Fatalf("Marshal() error = %v", err)
+	}
+	var receipt MessageReceipt
+	if err := codec.Unmarshal(data, &receipt); err != nil || receipt.MessageID != "msg-1" {
+		t.Errorf("Unmarshal() = %+v, %v", receipt, err)
+	}
+}
@@ -0,0 +1,91 @@
+package atoa
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// acceptGzip asks the server to compress the response. Setting the header
+// explicitly turns off the transport's own decompression, so responses must
+// go through decompressResponse.
+func acceptGzip(req *http.Request) {
+	req.Header.Set("Accept-Encoding", "gzip")
+}
+
+// decompressResponse replaces a gzip-encoded response body with its decoded
+// content. Responses in identity encoding are left unchanged.
+func decompressResponse(resp *http.Response) error {
+	if !strings.EqualFold(resp.Header.Get("Content-Encoding"), "gzip") {
+		return nil
+	}
+
+	zr, err := gzip.NewReader(resp.Body)
+	if err != nil {
+		return fmt.Errorf("failed to decompress response: %w", err)
+	}
+	resp.Body = &gzipBody{Reader: zr, body: resp.Body}
+	resp.Header.Del("Content-Encoding")
+	resp.Header.Del("Content-Length")
+	resp.ContentLength = -1
+	resp.Uncompressed = true
+	return nil
+}
+
+// gzipBody reads a decompressed response and closes the underlying body
+type gzipBody struct {
+	*gzip.Reader
+	body io.ReadCloser
+}
+
+// Close closes the gzip reader and the underlying body
+func (b *gzipBody) Close() error {
+	b.Reader.Close()
+	return b.body.Close()
+}
+
+// doCompressed sends req, whose body is body, gzip-compressing the body first
+// if it is larger than threshold. A threshold of zero or less disables
+// compression. Servers that reject the encoding with 415 Unsupported Media
+// Type get the request again uncompressed.
+func doCompressed(client *http.Client, req *http.Request, body []byte, threshold int) (*http.Response, error) {
+	if threshold <= 0 || len(body) <= threshold {
+		return client.Do(req)
+	}
+
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	if _, err := zw.Write(body); err != nil {
+		return nil, fmt.Errorf("failed to compress request: %w", err)
+	}
+	if err := zw.Close(); err != nil {
+		return nil, fmt.Errorf("failed to compress request: %w", err)
+	}
+
+	compressed := req.Clone(req.Context())
+	setRequestBody(compressed, buf.Bytes())
+	compressed.Header.Set("Content-Encoding", "gzip")
+
+	resp, err := client.Do(compressed)
+	if err != nil || resp.StatusCode != http.StatusUnsupportedMediaType {
+		return resp, err
+	}
+	resp.Body.Close()
+
+	// Fall back to identity encoding
+	plain := req.Clone(req.Context())
+	setRequestBody(plain, body)
+	return client.Do(plain)
+}
+
+// setRequestBody replaces the body of req with body
+func setRequestBody(req *http.Request, body []byte) {
+	req.Body = io.NopCloser(bytes.NewReader(body))
+	req.ContentLength = int64(len(body))
+	req.GetBody = func() (io.ReadCloser, error) {
+		return io.NopCloser(bytes.NewReader(body)), nil
+	}
+}
@@ -0,0 +1,141 @@
+package atoa
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// gzipBytes compresses data for use as a test response body
+func gzipBytes(t *testing.T, data []byte) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	if _, err := zw.Write(data); err != nil {
+		t.Fatalf("failed to compress: %v", err)
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatalf("failed to compress: %v", err)
+	}
+	return buf.Bytes()
+}
+
+func TestListOffers_GzipResponse(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if got := r.Header.Get("Accept-Encoding"); got != "gzip" {
+			t.Errorf("Accept-Encoding = %q, want %q", got, "gzip")
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Content-Encoding", "gzip")
+		w.Write(gzipBytes(t, []byte(`[{"header": {"id": "offer-1", "title": "Test Offer", "type": "service"}}]`)))
+	}))
+	defer ts.Close()
+
+	client := &AgentClient{BaseURL: ts.URL, HTTP: &http.Client{}}
+	offers, err := client.ListOffers(context.Background())
+	if err != nil {
+		t.Fatalf("ListOffers() error = %v", err)
+	}
+	if len(offers) != 1 || offers[0].Header.ID != "offer-1" {
+		t.Errorf("ListOffers() = %+v, want offer-1", offers)
+	}
+}
+
+func TestGetOffer_GzipErrorResponse(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Encoding", "gzip")
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write(gzipBytes(t, []byte("database unavailable")))
+	}))
+	defer ts.Close()
+
+	client := &AgentClient{BaseURL: ts.URL, HTTP: &http.Client{}}
+	_, err := client.GetOffer(context.Background(), "offer-1")
+
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) {
+		t.Fatalf("GetOffer() error = %v, want *APIError", err)
+	}
+	if apiErr.Message != "database unavailable" {
+		t.Errorf("APIError.Message = %q, want %q", apiErr.Message, "database unavailable")
+	}
+}
+
+func TestSendMessage_RequestCompression(t *testing.T) {
+	tests := []struct {
+		name          string
+		threshold     int
+		rejectGzip    bool
+		wantRequests  int
+		wantEncodings []string
+	}{
+		{name: "disabled", threshold: 0, wantRequests: 1, wantEncodings: []string{""}},
+		{name: "below threshold", threshold: 1 << 20, wantRequests: 1, wantEncodings: []string{""}},
+		{name: "above threshold", threshold: 64, wantRequests: 1, wantEncodings: []string{"gzip"}},
+		{name: "server without gzip", threshold: 64, rejectGzip: true, wantRequests: 2, wantEncodings: []string{"gzip", ""}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var encodings []string
+			ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				encoding := r.Header.Get("Content-Encoding")
+				encodings = append(encodings, encoding)
+
+				if encoding == "gzip" && tt.rejectGzip {
+					w.WriteHeader(http.StatusUnsupportedMediaType)
+					return
+				}
+
+				var body io.Reader = r.Body
+				if encoding == "gzip" {
+					zr, err := gzip.NewReader(r.Body)
+					if err != nil {
+						t.Errorf("request body is not gzip: %v", err)
+						w.WriteHeader(http.StatusBadRequest)
+						return
+					}
+					body = zr
+				}
+
+				var msg A2AMessage
+				if err := json.NewDecoder(body).Decode(&msg); err != nil {
+					t.Errorf("failed to decode message: %v", err)
+				}
+				w.WriteHeader(http.StatusOK)
+			}))
+			defer ts.Close()
+
+			client := NewAgentClient(ts.URL, WithRequestCompression(tt.threshold))
+			client.SetToken("valid-token")
+
+			err := client.SendMessage(context.Background(), A2AMessage{
+				SessionID:   "session-123",
+				FromAgentID: "agent-1",
+				ToAgentID:   "agent-2",
+				Type:        MessageTypeText,
+				Payload:     json.RawMessage(`{"content": "` + strings.Repeat("a", 256) + `"}`),
+				Timestamp:   time.Now(),
+			})
+			if err != nil {
+				t.Fatalf("SendMessage() error = %v", err)
+			}
+			if len(encodings) != tt.wantRequests {
+				t.Fatalf("server received %d requests, want %d", len(encodings), tt.wantRequests)
+			}
+			for i, want := range tt.wantEncodings {
+				if encodings[i] != want {
+					t.Errorf("request %d Content-Encoding = %q, want %q", i, encodings[i], want)
+				}
+			}
+		})
+	}
+}
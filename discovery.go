@@ -0,0 +1,116 @@
+package atoa
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+)
+
+// AgentJSONPath is the well-known path where A2A peers look for an agent's
+// discovery document
+const AgentJSONPath = "/.well-known/agent.json"
+
+// agentJSONMode is the media type agents exchange A2A messages in
+const agentJSONMode = "application/json"
+
+// agentJSON is the A2A agent card discovery document
+type agentJSON struct {
+	Name               string                `json:"name"`
+	URL                string                `json:"url"`
+	Provider           agentJSONProvider     `json:"provider"`
+	Version            string                `json:"version"`
+	Capabilities       agentJSONCapabilities `json:"capabilities"`
+	Authentication     agentJSONAuthScheme   `json:"authentication"`
+	DefaultInputModes  []string              `json:"defaultInputModes"`
+	DefaultOutputModes []string              `json:"defaultOutputModes"`
+	Skills             []agentJSONSkill      `json:"skills"`
+}
+
+type agentJSONProvider struct {
+	Organization string `json:"organization"`
+}
+
+// agentJSONCapabilities lists the optional A2A protocol features an agent
+// supports. Atoa agents support none of them.
+type agentJSONCapabilities struct {
+	Streaming              bool `json:"streaming"`
+	PushNotifications      bool `json:"pushNotifications"`
+	StateTransitionHistory bool `json:"stateTransitionHistory"`
+}
+
+type agentJSONSkill struct {
+	ID          string   `json:"id"`
+	Name        string   `json:"name"`
+	Description string   `json:"description"`
+	Tags        []string `json:"tags"`
+}
+
+type agentJSONAuthScheme struct {
+	Schemes []string `json:"schemes"`
+}
+
+// ToAgentJSON renders the card as an A2A agent.json discovery document. The
+// first endpoint is published as the agent URL and each capability as a
+// skill, so the card needs at least one endpoint and a Version.
+func (ac *AgentCard) ToAgentJSON() ([]byte, error) {
+	errs := ac.validationErrors()
+	if len(ac.Endpoints) == 0 {
+		errs.add("endpoints", "must contain the agent URL")
+	}
+	if ac.Version == "" {
+		errs.add("version", "is required")
+	}
+	if err := errs.err(); err != nil {
+		return nil, fmt.Errorf("invalid agent card: %w", err)
+	}
+
+	doc := agentJSON{
+		Name:               ac.AgentID,
+		URL:                ac.Endpoints[0],
+		Provider:           agentJSONProvider{Organization: ac.OrgID},
+		Version:            ac.Version,
+		DefaultInputModes:  []string{agentJSONMode},
+		DefaultOutputModes: []string{agentJSONMode},
+		Skills:             make([]agentJSONSkill, len(ac.Capabilities)),
+		// Agents authenticate to each other with platform-issued JWTs
+		Authentication: agentJSONAuthScheme{Schemes: []string{"bearer"}},
+	}
+	for i, capability := range ac.Capabilities {
+		doc.Skills[i] = agentJSONSkill{
+			ID:          capability,
+			Name:        capability,
+			Description: "Atoa capability " + capability,
+			Tags:        []string{capability},
+		}
+	}
+
+	body, err := json.Marshal(doc)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal agent.json: %w", err)
+	}
+	return body, nil
+}
+
+// AgentJSONHandler returns an http.Handler serving the card's discovery
+// document. Mount it at AgentJSONPath.
+func AgentJSONHandler(card *AgentCard) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			w.Header().Set("Allow", "GET, HEAD")
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+
+		body, err := card.ToAgentJSON()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		if r.Method == http.MethodGet {
+			w.Write(body)
+		}
+	})
+}
@@ -0,0 +1,146 @@
+package atoa
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestAgentCard_ToAgentJSON(t *testing.T) {
+	card := &AgentCard{
+		AgentID:      "test-agent",
+		OrgID:        "test-org",
+		Capabilities: []string{"text", "form"},
+		Endpoints:    []string{"https://agent.test.org"},
+		Version:      "1.2.0",
+	}
+
+	body, err := card.ToAgentJSON()
+	if err != nil {
+		t.Fatalf("ToAgentJSON() error = %v", err)
+	}
+
+	// Every field the A2A AgentCard schema requires is present
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(body, &fields); err != nil {
+		t.Fatalf("failed to decode agent.json: %v", err)
+	}
+	for _, field := range []string{"name", "url", "version", "capabilities", "defaultInputModes", "defaultOutputModes", "skills"} {
+		if _, ok := fields[field]; !ok {
+			t.Errorf("agent.json is missing required field %q", field)
+		}
+	}
+	if _, ok := fields["endpoints"]; ok {
+		t.Error("agent.json has non-spec field \"endpoints\"")
+	}
+
+	// The document decodes with the types of the A2A AgentCard schema
+	var doc struct {
+		Name     string `json:"name"`
+		URL      string `json:"url"`
+		Version  string `json:"version"`
+		Provider *struct {
+			Organization string `json:"organization"`
+		} `json:"provider"`
+		Capabilities struct {
+			Streaming              *bool `json:"streaming"`
+			PushNotifications      *bool `json:"pushNotifications"`
+			StateTransitionHistory *bool `json:"stateTransitionHistory"`
+		} `json:"capabilities"`
+		Authentication *struct {
+			Schemes []string `json:"schemes"`
+		} `json:"authentication"`
+		DefaultInputModes  []string `json:"defaultInputModes"`
+		DefaultOutputModes []string `json:"defaultOutputModes"`
+		Skills             []struct {
+			ID          string   `json:"id"`
+			Name        string   `json:"name"`
+			Description string   `json:"description"`
+			Tags        []string `json:"tags"`
+		} `json:"skills"`
+	}
+	if err := json.Unmarshal(body, &doc); err != nil {
+		t.Fatalf("agent.json does not match the A2A schema types: %v", err)
+	}
+
+	if doc.Name != "test-agent" {
+		t.Errorf("name = %v, want %v", doc.Name, "test-agent")
+	}
+	if doc.URL != "https://agent.test.org" {
+		t.Errorf("url = %v, want %v", doc.URL, "https://agent.test.org")
+	}
+	if doc.Version != "1.2.0" {
+		t.Errorf("version = %v, want %v", doc.Version, "1.2.0")
+	}
+	if doc.Provider == nil || doc.Provider.Organization != "test-org" {
+		t.Errorf("provider = %+v, want organization %v", doc.Provider, "test-org")
+	}
+	if doc.Capabilities.Streaming == nil || doc.Capabilities.PushNotifications == nil || doc.Capabilities.StateTransitionHistory == nil {
+		t.Errorf("capabilities = %s, want all flags set", fields["capabilities"])
+	}
+	if len(doc.DefaultInputModes) == 0 || len(doc.DefaultOutputModes) == 0 {
+		t.Errorf("default modes = %v / %v, want non-empty", doc.DefaultInputModes, doc.DefaultOutputModes)
+	}
+	if len(doc.Skills) != 2 {
+		t.Fatalf("len(skills) = %v, want %v", len(doc.Skills), 2)
+	}
+	for i, capability := range card.Capabilities {
+		skill := doc.Skills[i]
+		if skill.ID != capability || skill.Name == "" || skill.Description == "" || len(skill.Tags) == 0 {
+			t.Errorf("skills[%d] = %+v, want a complete skill for %q", i, skill, capability)
+		}
+	}
+	if doc.Authentication == nil || len(doc.Authentication.Schemes) == 0 {
+		t.Error("authentication.schemes is empty")
+	}
+
+	// Cards that cannot fill the required fields are rejected
+	invalid := []*AgentCard{
+		{AgentID: "test-agent"},
+		{AgentID: "test-agent", OrgID: "test-org", Capabilities: []string{"text"}, Version: "1.0.0"},
+		{AgentID: "test-agent", OrgID: "test-org", Capabilities: []string{"text"}, Endpoints: []string{"https://agent.test.org"}},
+	}
+	for _, card := range invalid {
+		if _, err := card.ToAgentJSON(); err == nil {
+			t.Errorf("ToAgentJSON(%+v) error = nil, want error", card)
+		}
+	}
+}
+
+func TestAgentJSONHandler(t *testing.T) {
+	card := &AgentCard{
+		AgentID:      "test-agent",
+		OrgID:        "test-org",
+		Capabilities: []string{"text"},
+		Endpoints:    []string{"https://agent.test.org"},
+		Version:      "1.0.0",
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle(AgentJSONPath, AgentJSONHandler(card))
+	ts := httptest.NewServer(mux)
+	defer ts.Close()
+
+	resp, err := http.Get(ts.URL + AgentJSONPath)
+	if err != nil {
+		t.Fatalf("GET agent.json error = %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("status = %d, want %d", resp.StatusCode, http.StatusOK)
+	}
+	if ct := resp.Header.Get("Content-Type"); ct != "application/json" {
+		t.Errorf("Content-Type = %v, want %v", ct, "application/json")
+	}
+
+	resp, err = http.Post(ts.URL+AgentJSONPath, "application/json", nil)
+	if err != nil {
+		t.Fatalf("POST agent.json error = %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusMethodNotAllowed {
+		t.Errorf("status = %d, want %d", resp.StatusCode, http.StatusMethodNotAllowed)
+	}
+}
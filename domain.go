@@ -0,0 +1,85 @@
+package atoa
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+)
+
+const (
+	// DomainChallengePrefix is the DNS label under an org's domain that holds
+	// the domain verification TXT record
+	DomainChallengePrefix = "_atoa-challenge."
+	// domainTokenPrefix starts every domain verification token
+	domainTokenPrefix = "atoa-verification="
+)
+
+// defaultResolver is used when VerifyDomainOwnership gets no resolver
+var defaultResolver TXTResolver = net.DefaultResolver
+
+// TXTResolver looks up DNS TXT records. *net.Resolver implements it; tests can
+// supply a stub.
+type TXTResolver interface {
+	LookupTXT(ctx context.Context, name string) ([]string, error)
+}
+
+// DomainVerificationToken returns the value an organization publishes in a
+// TXT record at _atoa-challenge.<domain> to prove it controls the domain. It
+// is derived from the SHA-256 fingerprint of the org's public key.
+func (oc *OrgCard) DomainVerificationToken() (string, error) {
+	block, _ := pem.Decode([]byte(oc.PublicKey))
+	if block == nil {
+		return "", errors.New("invalid public key format")
+	}
+	fingerprint := sha256.Sum256(block.Bytes)
+	return domainTokenPrefix + hex.EncodeToString(fingerprint[:]), nil
+}
+
+// VerifyDomainOwnership checks that the TXT records at
+// _atoa-challenge.<domain> contain the org's DomainVerificationToken. A nil
+// resolver uses the system resolver. It returns an error wrapping
+// ErrDomainRecordMissing when there is no record and ErrDomainRecordMismatch
+// when no record holds the expected token.
+func (oc *OrgCard) VerifyDomainOwnership(ctx context.Context, resolver TXTResolver) error {
+	if oc.Domain == "" {
+		return errors.New("domain is required")
+	}
+	want, err := oc.DomainVerificationToken()
+	if err != nil {
+		return err
+	}
+	if resolver == nil {
+		resolver = defaultResolver
+	}
+
+	name := DomainChallengePrefix + strings.TrimSuffix(oc.Domain, ".")
+	records, err := resolver.LookupTXT(ctx, name)
+	if err != nil {
+		if isNotFound(err) {
+			return fmt.Errorf("%w: %s", ErrDomainRecordMissing, name)
+		}
+		return fmt.Errorf("failed to look up %s: %w", name, err)
+	}
+	if len(records) == 0 {
+		return fmt.Errorf("%w: %s", ErrDomainRecordMissing, name)
+	}
+
+	for _, record := range records {
+		if strings.TrimSpace(record) == want {
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: %s does not contain the token for org %s", ErrDomainRecordMismatch, name, oc.OrgID)
+}
+
+// isNotFound reports whether a DNS lookup failed because the name has no
+// records
+func isNotFound(err error) bool {
+	var dnsErr *net.DNSError
+	return errors.As(err, &dnsErr) && dnsErr.IsNotFound
+}
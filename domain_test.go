@@ -0,0 +1,83 @@
+package atoa
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+)
+
+// stubResolver serves TXT records from a map
+type stubResolver map[string][]string
+
+func (r stubResolver) LookupTXT(ctx context.Context, name string) ([]string, error) {
+	records, ok := r[name]
+	if !ok {
+		return nil, &net.DNSError{Err: "no such host", Name: name, IsNotFound: true}
+	}
+	return records, nil
+}
+
+func TestOrgCard_VerifyDomainOwnership(t *testing.T) {
+	card := &OrgCard{
+		OrgID:     "test-org",
+		Name:      "Test Org",
+		Domain:    "test.org",
+		PublicKey: generateTestPublicKey(t),
+	}
+	token, err := card.DomainVerificationToken()
+	if err != nil {
+		t.Fatalf("DomainVerificationToken() error = %v", err)
+	}
+
+	otherCard := &OrgCard{PublicKey: generateTestPublicKey(t)}
+	otherToken, err := otherCard.DomainVerificationToken()
+	if err != nil {
+		t.Fatalf("DomainVerificationToken() error = %v", err)
+	}
+	if token == otherToken {
+		t.Fatal("different keys produce the same token")
+	}
+
+	tests := []struct {
+		name     string
+		resolver stubResolver
+		wantErr  error
+	}{
+		{
+			name:     "matching record",
+			resolver: stubResolver{"_atoa-challenge.test.org": {"v=spf1 -all", token}},
+			wantErr:  nil,
+		},
+		{
+			name:     "missing record",
+			resolver: stubResolver{},
+			wantErr:  ErrDomainRecordMissing,
+		},
+		{
+			name:     "empty record set",
+			resolver: stubResolver{"_atoa-challenge.test.org": {}},
+			wantErr:  ErrDomainRecordMissing,
+		},
+		{
+			name:     "record for another key",
+			resolver: stubResolver{"_atoa-challenge.test.org": {otherToken}},
+			wantErr:  ErrDomainRecordMismatch,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := card.VerifyDomainOwnership(context.Background(), tt.resolver)
+			if tt.wantErr == nil {
+				if err != nil {
+					t.Errorf("VerifyDomainOwnership() error = %v", err)
+				}
+				return
+			}
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("VerifyDomainOwnership() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
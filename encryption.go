@@ -0,0 +1,156 @@
+package atoa
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// EncryptionAlgorithm names the payload encryption scheme: ephemeral-static
+// ECDH on P-256, HKDF-SHA256 key derivation and AES-256-GCM
+const EncryptionAlgorithm = "ECDH-ES+A256GCM"
+
+// encryptionInfo binds derived keys to this scheme
+const encryptionInfo = "atoa payload encryption v1"
+
+// RecipientKeyFunc returns the PEM-encoded P-256 public key of an agent, for
+// encrypting payloads sent to it
+type RecipientKeyFunc func(ctx context.Context, agentID string) (string, error)
+
+// encryptedPayload is the envelope that replaces an encrypted message payload
+type encryptedPayload struct {
+	Algorithm string `json:"alg"`
+	// EphemeralKey is the sender's one-time public key in uncompressed form
+	EphemeralKey []byte `json:"epk"`
+	Nonce        []byte `json:"nonce"`
+	Ciphertext   []byte `json:"ciphertext"`
+}
+
+// EncryptPayload encrypts plaintext so that only the holder of the private key
+// matching recipientPublicKeyPEM can read it. The result is a JSON envelope
+// carrying the ephemeral public key, nonce and ciphertext.
+func EncryptPayload(plaintext []byte, recipientPublicKeyPEM string) (json.RawMessage, error) {
+	recipientECDSA, err := ParsePublicKeyPEM(recipientPublicKeyPEM)
+	if err != nil {
+		return nil, fmt.Errorf("invalid recipient key: %w", err)
+	}
+	if recipientECDSA.Curve != elliptic.P256() {
+		return nil, errors.New("recipient key must be on P-256")
+	}
+	recipient, err := recipientECDSA.ECDH()
+	if err != nil {
+		return nil, fmt.Errorf("invalid recipient key: %w", err)
+	}
+
+	ephemeral, err := ecdh.P256().GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate ephemeral key: %w", err)
+	}
+	shared, err := ephemeral.ECDH(recipient)
+	if err != nil {
+		return nil, fmt.Errorf("failed to derive shared secret: %w", err)
+	}
+
+	epk := ephemeral.PublicKey().Bytes()
+	gcm, err := newPayloadCipher(shared, epk, recipient.Bytes())
+	if err != nil {
+		return nil, err
+	}
+
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, fmt.Errorf("failed to generate nonce: %w", err)
+	}
+
+	envelope, err := json.Marshal(encryptedPayload{
+		Algorithm:    EncryptionAlgorithm,
+		EphemeralKey: epk,
+		Nonce:        nonce,
+		Ciphertext:   gcm.Seal(nil, nonce, plaintext, nil),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode encrypted payload: %w", err)
+	}
+	return envelope, nil
+}
+
+// DecryptPayload opens an envelope produced by EncryptPayload
+func DecryptPayload(payload json.RawMessage, recipientPrivateKey *ecdsa.PrivateKey) ([]byte, error) {
+	if recipientPrivateKey == nil {
+		return nil, errors.New("private key is required")
+	}
+
+	var envelope encryptedPayload
+	if err := json.Unmarshal(payload, &envelope); err != nil {
+		return nil, fmt.Errorf("invalid encrypted payload: %w", err)
+	}
+	if envelope.Algorithm != EncryptionAlgorithm {
+		return nil, fmt.Errorf("unsupported encryption algorithm %q", envelope.Algorithm)
+	}
+
+	private, err := recipientPrivateKey.ECDH()
+	if err != nil {
+		return nil, fmt.Errorf("invalid private key: %w", err)
+	}
+	ephemeral, err := ecdh.P256().NewPublicKey(envelope.EphemeralKey)
+	if err != nil {
+		return nil, fmt.Errorf("invalid ephemeral key: %w", err)
+	}
+	shared, err := private.ECDH(ephemeral)
+	if err != nil {
+		return nil, fmt.Errorf("failed to derive shared secret: %w", err)
+	}
+
+	gcm, err := newPayloadCipher(shared, envelope.EphemeralKey, private.PublicKey().Bytes())
+	if err != nil {
+		return nil, err
+	}
+	if len(envelope.Nonce) != gcm.NonceSize() {
+		return nil, errors.New("invalid nonce size")
+	}
+
+	plaintext, err := gcm.Open(nil, envelope.Nonce, envelope.Ciphertext, nil)
+	if err != nil {
+		return nil, errors.New("failed to decrypt payload: message authentication failed")
+	}
+	return plaintext, nil
+}
+
+// newPayloadCipher derives the AES-256-GCM key from the ECDH shared secret,
+// salted with both public keys so the key is bound to this exchange
+func newPayloadCipher(shared, ephemeralKey, recipientKey []byte) (cipher.AEAD, error) {
+	salt := append(append([]byte{}, ephemeralKey...), recipientKey...)
+	key := hkdfSHA256(shared, salt, []byte(encryptionInfo))
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cipher: %w", err)
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cipher: %w", err)
+	}
+	return gcm, nil
+}
+
+// hkdfSHA256 derives a 32-byte key with HKDF-SHA256 (RFC 5869). One output
+// block is exactly the AES-256 key size.
+func hkdfSHA256(secret, salt, info []byte) []byte {
+	extract := hmac.New(sha256.New, salt)
+	extract.Write(secret)
+	prk := extract.Sum(nil)
+
+	expand := hmac.New(sha256.New, prk)
+	expand.Write(info)
+	expand.Write([]byte{1})
+	return expand.Sum(nil)
+}
@@ -0,0 +1,213 @@
+package atoa
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// newEncryptionKey returns a P-256 key pair with the public half PEM-encoded
+func newEncryptionKey(t *testing.T) (*ecdsa.PrivateKey, string) {
+	t.Helper()
+	privateKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("GenerateKey() error = %v", err)
+	}
+	publicKeyPEM, err := MarshalPublicKeyPEM(&privateKey.PublicKey)
+	if err != nil {
+		t.Fatalf("MarshalPublicKeyPEM() error = %v", err)
+	}
+	return privateKey, publicKeyPEM
+}
+
+func TestEncryptPayload_Roundtrip(t *testing.T) {
+	privateKey, publicKeyPEM := newEncryptionKey(t)
+	plaintext := []byte(`{"content": "Hello"}`)
+
+	envelope, err := EncryptPayload(plaintext, publicKeyPEM)
+	if err != nil {
+		t.Fatalf("EncryptPayload() error = %v", err)
+	}
+	if bytes.Contains(envelope, []byte("Hello")) {
+		t.Error("envelope contains the plaintext")
+	}
+
+	var fields map[string]any
+	if err := json.Unmarshal(envelope, &fields); err != nil {
+		t.Fatalf("envelope is not JSON: %v", err)
+	}
+	for _, field := range []string{"alg", "epk", "nonce", "ciphertext"} {
+		if _, ok := fields[field]; !ok {
+			t.Errorf("envelope is missing %q", field)
+		}
+	}
+
+	got, err := DecryptPayload(envelope, privateKey)
+	if err != nil {
+		t.Fatalf("DecryptPayload() error = %v", err)
+	}
+	if !bytes.Equal(got, plaintext) {
+		t.Errorf("DecryptPayload() = %s, want %s", got, plaintext)
+	}
+
+	// Each call uses a fresh ephemeral key and nonce
+	other, err := EncryptPayload(plaintext, publicKeyPEM)
+	if err != nil {
+		t.Fatalf("EncryptPayload() error = %v", err)
+	}
+	if bytes.Equal(envelope, other) {
+		t.Error("two encryptions of the same plaintext are equal")
+	}
+}
+
+func TestDecryptPayload_Errors(t *testing.T) {
+	privateKey, publicKeyPEM := newEncryptionKey(t)
+	otherKey, _ := newEncryptionKey(t)
+
+	envelope, err := EncryptPayload([]byte(`{"content": "Hello"}`), publicKeyPEM)
+	if err != nil {
+		t.Fatalf("EncryptPayload() error = %v", err)
+	}
+
+	var tampered encryptedPayload
+	if err := json.Unmarshal(envelope, &tampered); err != nil {
+		t.Fatalf("Unmarshal() error = %v", err)
+	}
+	tampered.Ciphertext[0] ^= 0xff
+	tamperedEnvelope, _ := json.Marshal(tampered)
+
+	tests := []struct {
+		name    string
+		payload json.RawMessage
+		key     *ecdsa.PrivateKey
+	}{
+		{name: "wrong key", payload: envelope, key: otherKey},
+		{name: "tampered ciphertext", payload: tamperedEnvelope, key: privateKey},
+		{name: "unknown algorithm", payload: json.RawMessage(`{"alg": "none"}`), key: privateKey},
+		{name: "not an envelope", payload: json.RawMessage(`"text"`), key: privateKey},
+		{name: "nil key", payload: envelope, key: nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := DecryptPayload(tt.payload, tt.key); err == nil {
+				t.Error("DecryptPayload() error = nil, want error")
+			}
+		})
+	}
+}
+
+func TestEncryptPayload_InvalidKey(t *testing.T) {
+	if _, err := EncryptPayload([]byte(`{}`), "not a key"); err == nil {
+		t.Error("EncryptPayload() error = nil, want error")
+	}
+
+	p384Key, err := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	if err != nil {
+		t.Fatalf("GenerateKey() error = %v", err)
+	}
+	publicKeyPEM, err := MarshalPublicKeyPEM(&p384Key.PublicKey)
+	if err != nil {
+		t.Fatalf("MarshalPublicKeyPEM() error = %v", err)
+	}
+	if _, err := EncryptPayload([]byte(`{}`), publicKeyPEM); err == nil {
+		t.Error("EncryptPayload() with a P-384 key error = nil, want error")
+	}
+}
+
+func TestSendMessage_RecipientKey(t *testing.T) {
+	privateKey, publicKeyPEM := newEncryptionKey(t)
+
+	var received A2AMessage
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := json.NewDecoder(r.Body).Decode(&received); err != nil {
+			t.Errorf("failed to decode message: %v", err)
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	var lookedUp string
+	client := &AgentClient{
+		BaseURL: server.URL,
+		HTTP:    &http.Client{},
+		RecipientKey: func(ctx context.Context, agentID string) (string, error) {
+			lookedUp = agentID
+			return publicKeyPEM, nil
+		},
+	}
+	client.SetToken("valid-token")
+
+	plaintext := json.RawMessage(`{"content":"Hello"}`)
+	err := client.SendMessage(context.Background(), A2AMessage{
+		SessionID:   "session-123",
+		FromAgentID: "agent-1",
+		ToAgentID:   "agent-2",
+		Type:        MessageTypeText,
+		Payload:     plaintext,
+		Timestamp:   time.Now(),
+	})
+	if err != nil {
+		t.Fatalf("SendMessage() error = %v", err)
+	}
+
+	if lookedUp != "agent-2" {
+		t.Errorf("RecipientKey called with %q, want %q", lookedUp, "agent-2")
+	}
+	if !received.Encrypted {
+		t.Error("received message is not marked encrypted")
+	}
+	got, err := DecryptPayload(received.Payload, privateKey)
+	if err != nil {
+		t.Fatalf("DecryptPayload() error = %v", err)
+	}
+	if !bytes.Equal(got, plaintext) {
+		t.Errorf("decrypted payload = %s, want %s", got, plaintext)
+	}
+}
+
+func TestSendMessage_RecipientKeyPayloadLimit(t *testing.T) {
+	_, publicKeyPEM := newEncryptionKey(t)
+
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	// The plaintext fits the limit but the envelope around it does not
+	plaintext := json.RawMessage(`{"content":"Hello"}`)
+	client := &AgentClient{
+		BaseURL:         server.URL,
+		HTTP:            &http.Client{},
+		MaxPayloadBytes: len(plaintext) + 10,
+		RecipientKey: func(ctx context.Context, agentID string) (string, error) {
+			return publicKeyPEM, nil
+		},
+	}
+	client.SetToken("valid-token")
+
+	err := client.SendMessage(context.Background(), A2AMessage{
+		SessionID:   "session-123",
+		FromAgentID: "agent-1",
+		ToAgentID:   "agent-2",
+		Type:        MessageTypeText,
+		Payload:     plaintext,
+		Timestamp:   time.Now(),
+	})
+	if !errors.Is(err, ErrPayloadTooLarge) {
+		t.Errorf("SendMessage() error = %v, want %v", err, ErrPayloadTooLarge)
+	}
+	if requests != 0 {
+		t.Errorf("server received %d requests, want 0", requests)
+	}
+}
@@ -0,0 +1,142 @@
+package atoa
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// maxErrorBodyBytes caps how much of an error response body is kept in an
+// APIError, so a misbehaving server cannot flood logs
+const maxErrorBodyBytes = 1024
+
+var (
+	// ErrAgentNotVerified is returned when a client-side check finds that the
+	// agent token does not carry a verified status
+	ErrAgentNotVerified = errors.New("agent is not verified")
+
+	// ErrChallengeExpired is returned when a signature is checked against a
+	// challenge whose lifetime has passed
+	ErrChallengeExpired = errors.New("challenge is expired")
+
+	// ErrChallengeUsed is returned by VerifyChallengeSignatureOnce when the
+	// challenge has already been answered
+	ErrChallengeUsed = errors.New("challenge already used")
+
+	// ErrClientClosed is returned by requests made after Close
+	ErrClientClosed = errors.New("client is closed")
+
+	// ErrPayloadTooLarge is returned when a message payload exceeds the
+	// client's size limit
+	ErrPayloadTooLarge = errors.New("payload too large")
+
+	// ErrOfferNotFound is returned when the platform has no offer with the
+	// requested ID
+	ErrOfferNotFound = errors.New("offer not found")
+
+	// ErrAlreadyBookmarked is returned by BookmarkOffer when the offer is
+	// already among the agent's bookmarks
+	ErrAlreadyBookmarked = errors.New("offer already bookmarked")
+
+	// ErrUnreachable is returned by Ping when the platform cannot be reached
+	// at the network level
+	ErrUnreachable = errors.New("platform unreachable")
+
+	// ErrDomainRecordMissing is returned by VerifyDomainOwnership when the
+	// domain has no verification TXT record
+	ErrDomainRecordMissing = errors.New("domain verification record missing")
+
+	// ErrDomainRecordMismatch is returned by VerifyDomainOwnership when the
+	// domain's TXT records do not contain the org's token
+	ErrDomainRecordMismatch = errors.New("domain verification record mismatch")
+)
+
+// APIError is returned when the platform responds with an unexpected status
+type APIError struct {
+	StatusCode int
+	// Message is the response body, truncated to 1KB
+	Message string
+}
+
+// Error implements the error interface
+func (e *APIError) Error() string {
+	if e.Message == "" {
+		return fmt.Sprintf("unexpected status code: %d", e.StatusCode)
+	}
+	return fmt.Sprintf("unexpected status code: %d: %s", e.StatusCode, e.Message)
+}
+
+// newAPIError builds an APIError from a response, reading at most
+// maxErrorBodyBytes of its body
+func newAPIError(resp *http.Response) *APIError {
+	// An undecodable body is kept as is; it is only used for the message
+	_ = decompressResponse(resp)
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorBodyBytes+1))
+
+	message := string(body)
+	if len(body) > maxErrorBodyBytes {
+		message = string(body[:maxErrorBodyBytes]) + "...(truncated)"
+	}
+
+	return &APIError{
+		StatusCode: resp.StatusCode,
+		Message:    strings.TrimSpace(message),
+	}
+}
+
+// hasStatus reports whether err is an APIError with the given status code
+func hasStatus(err error, statusCode int) bool {
+	var apiErr *APIError
+	return errors.As(err, &apiErr) && apiErr.StatusCode == statusCode
+}
+
+// ValidationError describes one problem with a field of a validated value
+type ValidationError struct {
+	// Field is the JSON name of the offending field
+	Field string
+	// Reason says what is wrong with it, e.g. "is required"
+	Reason string
+}
+
+// Error implements the error interface
+func (e *ValidationError) Error() string {
+	return e.Field + " " + e.Reason
+}
+
+// ValidationErrors collects every problem found by a Validate method, so
+// callers can fix them all in one pass. errors.As can extract either the
+// whole list or its first *ValidationError.
+type ValidationErrors []*ValidationError
+
+// Error implements the error interface
+func (e ValidationErrors) Error() string {
+	messages := make([]string, len(e))
+	for i, err := range e {
+		messages[i] = err.Error()
+	}
+	return strings.Join(messages, "; ")
+}
+
+// Unwrap returns the individual errors for errors.Is and errors.As
+func (e ValidationErrors) Unwrap() []error {
+	errs := make([]error, len(e))
+	for i, err := range e {
+		errs[i] = err
+	}
+	return errs
+}
+
+// add records a problem with field
+func (e *ValidationErrors) add(field, reason string) {
+	*e = append(*e, &ValidationError{Field: field, Reason: reason})
+}
+
+// err returns the collected errors, or nil when there are none
+func (e ValidationErrors) err() error {
+	if len(e) == 0 {
+		return nil
+	}
+	return e
+}
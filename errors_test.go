@@ -0,0 +1,129 @@
+package atoa
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestAPIError_IncludesResponseBody(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "agent is suspended", http.StatusForbidden)
+	}))
+	defer ts.Close()
+
+	client := &AgentClient{
+		BaseURL: ts.URL,
+		HTTP:    &http.Client{},
+	}
+	client.SetToken("valid-token")
+
+	_, err := client.ListOffers(context.Background())
+
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) {
+		t.Fatalf("ListOffers() error = %v, want *APIError", err)
+	}
+	if apiErr.StatusCode != http.StatusForbidden {
+		t.Errorf("StatusCode = %v, want %v", apiErr.StatusCode, http.StatusForbidden)
+	}
+	if apiErr.Message != "agent is suspended" {
+		t.Errorf("Message = %q, want %q", apiErr.Message, "agent is suspended")
+	}
+
+	// Flows with their own error prefix still expose the APIError
+	_, err = NewOrgClient(ts.URL).RequestToken("test-org", "challenge", "signature")
+	if !errors.As(err, &apiErr) {
+		t.Errorf("RequestToken() error = %v, want *APIError", err)
+	}
+}
+
+func TestAPIError_TruncatesLargeBody(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte(strings.Repeat("x", 10*maxErrorBodyBytes)))
+	}))
+	defer ts.Close()
+
+	err := NewAgentClient(ts.URL).JoinSession("session-1", "valid-token")
+
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) {
+		t.Fatalf("JoinSession() error = %v, want *APIError", err)
+	}
+	if len(apiErr.Message) > maxErrorBodyBytes+len("...(truncated)") {
+		t.Errorf("len(Message) = %v, want at most %v", len(apiErr.Message), maxErrorBodyBytes)
+	}
+	if !strings.HasSuffix(apiErr.Message, "(truncated)") {
+		t.Error("Message is not marked as truncated")
+	}
+}
+
+func TestValidationErrors_ReportsEveryField(t *testing.T) {
+	tests := []struct {
+		name       string
+		validate   func() error
+		wantFields []string
+	}{
+		{
+			name:       "agent card",
+			validate:   (&AgentCard{}).Validate,
+			wantFields: []string{"agent_id", "org_id", "capabilities"},
+		},
+		{
+			name:       "org card",
+			validate:   (&OrgCard{OrgID: "org-1", PublicKey: "not pem"}).Validate,
+			wantFields: []string{"name", "domain", "public_key"},
+		},
+		{
+			name:       "message",
+			validate:   (&A2AMessage{SessionID: "session-123", Type: "unknown"}).Validate,
+			wantFields: []string{"from_agent_id", "to_agent_id", "type", "payload", "timestamp"},
+		},
+		{
+			name:       "ordered message",
+			validate:   (&A2AMessage{}).ValidateOrdered,
+			wantFields: []string{"session_id", "from_agent_id", "to_agent_id", "type", "payload", "timestamp", "seq"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.validate()
+
+			var errs ValidationErrors
+			if !errors.As(err, &errs) {
+				t.Fatalf("Validate() error = %v, want ValidationErrors", err)
+			}
+			if len(errs) != len(tt.wantFields) {
+				t.Fatalf("Validate() = %v, want fields %v", errs, tt.wantFields)
+			}
+			for i, fieldErr := range errs {
+				if fieldErr.Field != tt.wantFields[i] {
+					t.Errorf("errs[%d].Field = %q, want %q", i, fieldErr.Field, tt.wantFields[i])
+				}
+			}
+
+			// The first problem is also reachable on its own
+			var fieldErr *ValidationError
+			if !errors.As(err, &fieldErr) || fieldErr.Field != tt.wantFields[0] {
+				t.Errorf("errors.As(*ValidationError) = %v, want field %q", fieldErr, tt.wantFields[0])
+			}
+		})
+	}
+}
+
+func TestValidationErrors_Error(t *testing.T) {
+	err := (&AgentCard{AgentID: "agent-1"}).Validate()
+	want := "org_id is required; capabilities must contain at least one capability"
+	if err == nil || err.Error() != want {
+		t.Errorf("Validate() error = %v, want %q", err, want)
+	}
+
+	if err := (&AgentCard{AgentID: "agent-1", OrgID: "org-1", Capabilities: []string{"text"}}).Validate(); err != nil {
+		t.Errorf("Validate() of a valid card error = %v, want nil", err)
+	}
+}
@@ -0,0 +1,62 @@
+package atoa
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// DefaultPingTimeout bounds a Ping call when ctx has no earlier deadline
+const DefaultPingTimeout = 5 * time.Second
+
+// Ping checks that the platform is reachable by requesting /health. It returns
+// an error wrapping ErrUnreachable on network failures and an *APIError on
+// non-2xx responses. When ctx is canceled or expires, its error is returned
+// as is.
+func (c *OrgClient) Ping(ctx context.Context) error {
+	return ping(ctx, c.HTTP, c.endpoint("/health"), c.setHeaders)
+}
+
+// Ping checks that the platform is reachable by requesting /health. It returns
+// an error wrapping ErrUnreachable on network failures and an *APIError on
+// non-2xx responses. When ctx is canceled or expires, its error is returned
+// as is.
+func (c *AgentClient) Ping(ctx context.Context) error {
+	ctx, done, err := c.beginRequest(ctx)
+	if err != nil {
+		return err
+	}
+	defer done()
+
+	return ping(ctx, c.HTTP, c.endpoint("/health"), c.setHeaders)
+}
+
+// ping performs the health check shared by both clients
+func ping(ctx context.Context, client *http.Client, healthURL string, setHeaders func(*http.Request)) error {
+	pingCtx, cancel := context.WithTimeout(ctx, DefaultPingTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(pingCtx, http.MethodGet, healthURL, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	setHeaders(req)
+
+	resp, err := client.Do(req)
+	if err != nil {
+		// The caller giving up says nothing about the platform; only the
+		// DefaultPingTimeout running out counts as unreachable
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		return fmt.Errorf("%w: %w", ErrUnreachable, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return newAPIError(resp)
+	}
+
+	return nil
+}
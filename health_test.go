@@ -0,0 +1,92 @@
+package atoa
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestPing(t *testing.T) {
+	healthy := true
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/health" {
+			t.Errorf("expected path /health, got %s", r.URL.Path)
+		}
+		if !healthy {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer ts.Close()
+
+	if err := NewAgentClient(ts.URL).Ping(context.Background()); err != nil {
+		t.Errorf("AgentClient.Ping() error = %v", err)
+	}
+	if err := NewOrgClient(ts.URL).Ping(context.Background()); err != nil {
+		t.Errorf("OrgClient.Ping() error = %v", err)
+	}
+
+	// Non-2xx responses are reachable but unhealthy
+	healthy = false
+	var apiErr *APIError
+	err := NewAgentClient(ts.URL).Ping(context.Background())
+	if !errors.As(err, &apiErr) || errors.Is(err, ErrUnreachable) {
+		t.Errorf("Ping() error = %v, want *APIError", err)
+	}
+
+	// Network failures are reported as unreachable
+	url := ts.URL
+	ts.Close()
+	if err := NewOrgClient(url).Ping(context.Background()); !errors.Is(err, ErrUnreachable) {
+		t.Errorf("Ping() error = %v, want %v", err, ErrUnreachable)
+	}
+}
+
+func TestPing_ContextDone(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+	}))
+	defer ts.Close()
+
+	canceled, cancel := context.WithCancel(context.Background())
+	cancel()
+	expired, cancelExpired := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancelExpired()
+
+	tests := []struct {
+		name string
+		ctx  context.Context
+		want error
+	}{
+		{"canceled", canceled, context.Canceled},
+		{"deadline exceeded", expired, context.DeadlineExceeded},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := NewAgentClient(ts.URL).Ping(tt.ctx)
+			if !errors.Is(err, tt.want) {
+				t.Errorf("Ping() error = %v, want %v", err, tt.want)
+			}
+			if errors.Is(err, ErrUnreachable) {
+				t.Errorf("Ping() error = %v, should not be %v", err, ErrUnreachable)
+			}
+		})
+	}
+}
+
+func TestPing_WrapsCause(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	url := ts.URL
+	ts.Close()
+
+	err := NewOrgClient(url).Ping(context.Background())
+	var netErr net.Error
+	if !errors.Is(err, ErrUnreachable) || !errors.As(err, &netErr) {
+		t.Errorf("Ping() error = %v, want %v wrapping the network error", err, ErrUnreachable)
+	}
+}
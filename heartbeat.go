@@ -0,0 +1,58 @@
+package atoa
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+)
+
+// Heartbeat tells the platform that this agent is online
+func (c *AgentClient) Heartbeat(ctx context.Context) error {
+	return c.doJSON(ctx, http.MethodPost, "/agents/heartbeat", nil, nil)
+}
+
+// StartHeartbeat starts a background loop that sends a heartbeat right away
+// and then every interval. The loop stops when ctx is canceled or the client
+// is closed. LastHeartbeatError reports how the latest heartbeat went.
+func (c *AgentClient) StartHeartbeat(ctx context.Context, interval time.Duration) error {
+	if interval <= 0 {
+		return errors.New("heartbeat interval must be positive")
+	}
+	go c.heartbeat(ctx, interval)
+	return nil
+}
+
+// LastHeartbeatError returns the result of the most recent background
+// heartbeat, or nil if it succeeded or none has been sent yet
+func (c *AgentClient) LastHeartbeatError() error {
+	c.heartbeatMu.Lock()
+	defer c.heartbeatMu.Unlock()
+	return c.lastHeartbeatErr
+}
+
+// heartbeat runs the loop started by StartHeartbeat
+func (c *AgentClient) heartbeat(ctx context.Context, interval time.Duration) {
+	lifetime := c.lifetime()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		err := c.Heartbeat(ctx)
+		if ctx.Err() != nil || lifetime.Err() != nil {
+			// A heartbeat cut short by shutdown says nothing about health
+			return
+		}
+		c.heartbeatMu.Lock()
+		c.lastHeartbeatErr = err
+		c.heartbeatMu.Unlock()
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-lifetime.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
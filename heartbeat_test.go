@@ -0,0 +1,88 @@
+package atoa
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestAgentClient_Heartbeat(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/agents/heartbeat" {
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+		}
+		if r.Header.Get("Authorization") != "Bearer valid-token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer ts.Close()
+
+	client := NewAgentClient(ts.URL)
+	client.SetToken("valid-token")
+	if err := client.Heartbeat(context.Background()); err != nil {
+		t.Errorf("Heartbeat() error = %v", err)
+	}
+
+	client.SetToken("expired-token")
+	if err := client.Heartbeat(context.Background()); !hasStatus(err, http.StatusUnauthorized) {
+		t.Errorf("Heartbeat() error = %v, want 401 APIError", err)
+	}
+}
+
+func TestAgentClient_StartHeartbeat(t *testing.T) {
+	var beats, failing int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&beats, 1)
+		if atomic.LoadInt32(&failing) == 1 {
+			http.Error(w, "agent unknown", http.StatusNotFound)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer ts.Close()
+
+	client := NewAgentClient(ts.URL)
+	client.SetToken("valid-token")
+
+	if err := client.StartHeartbeat(context.Background(), 0); err == nil {
+		t.Error("StartHeartbeat() with zero interval error = nil, want error")
+	}
+	if err := client.StartHeartbeat(context.Background(), 20*time.Millisecond); err != nil {
+		t.Fatalf("StartHeartbeat() error = %v", err)
+	}
+
+	deadline := time.Now().Add(2 * time.Second)
+	for atomic.LoadInt32(&beats) < 3 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if got := atomic.LoadInt32(&beats); got < 3 {
+		t.Fatalf("heartbeats = %d, want at least 3", got)
+	}
+	if err := client.LastHeartbeatError(); err != nil {
+		t.Errorf("LastHeartbeatError() = %v, want nil", err)
+	}
+
+	// Failures are surfaced through LastHeartbeatError
+	atomic.StoreInt32(&failing, 1)
+	deadline = time.Now().Add(2 * time.Second)
+	for client.LastHeartbeatError() == nil && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if !hasStatus(client.LastHeartbeatError(), http.StatusNotFound) {
+		t.Errorf("LastHeartbeatError() = %v, want 404 APIError", client.LastHeartbeatError())
+	}
+
+	// Close stops the loop
+	client.Close()
+	time.Sleep(50 * time.Millisecond)
+	stopped := atomic.LoadInt32(&beats)
+	time.Sleep(100 * time.Millisecond)
+	if got := atomic.LoadInt32(&beats); got != stopped {
+		t.Errorf("heartbeats after Close = %d, want %d", got, stopped)
+	}
+}
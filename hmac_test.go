@@ -0,0 +1,117 @@
+package atoa
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWithHMACAuth(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	defer SetTimeFunc(func() time.Time { return now })()
+
+	var gotBody []byte
+	var gotHeader http.Header
+	var gotPath, gotMethod string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotMethod, gotPath, gotHeader = r.Method, r.RequestURI, r.Header
+		gotBody, _ = io.ReadAll(r.Body)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer ts.Close()
+
+	client := NewAgentClient(ts.URL, WithHMACAuth("key-1", "shared-secret"))
+	client.SetToken("ignored-token")
+
+	err := client.SendMessage(context.Background(), A2AMessage{
+		SessionID:   "session-123",
+		FromAgentID: "agent-1",
+		ToAgentID:   "agent-2",
+		Type:        MessageTypeText,
+		Payload:     json.RawMessage(`{"content": "Hello"}`),
+		Timestamp:   now,
+	})
+	if err != nil {
+		t.Fatalf("SendMessage() error = %v", err)
+	}
+
+	if got := gotHeader.Get("Authorization"); got != "" {
+		t.Errorf("Authorization = %q, want no bearer token", got)
+	}
+	if got := gotHeader.Get(HeaderKeyID); got != "key-1" {
+		t.Errorf("%s = %q, want %q", HeaderKeyID, got, "key-1")
+	}
+	timestamp := strconv.FormatInt(now.Unix(), 10)
+	if got := gotHeader.Get(HeaderTimestamp); got != timestamp {
+		t.Errorf("%s = %q, want %q", HeaderTimestamp, got, timestamp)
+	}
+	if len(gotBody) == 0 {
+		t.Fatal("server received an empty body")
+	}
+
+	want := hmacSignature([]byte("shared-secret"), gotMethod, gotPath, gotBody, timestamp)
+	if got := gotHeader.Get(HeaderSignature); got != want {
+		t.Errorf("%s = %q, want %q", HeaderSignature, got, want)
+	}
+
+	// A different secret produces a different signature
+	if other := hmacSignature([]byte("other-secret"), gotMethod, gotPath, gotBody, timestamp); other == want {
+		t.Error("signatures with different secrets are equal")
+	}
+}
+
+func TestWithHMACAuth_GetRequest(t *testing.T) {
+	var gotSignature string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotSignature = r.Header.Get(HeaderSignature)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`[]`))
+	}))
+	defer ts.Close()
+
+	client := NewAgentClient(ts.URL, WithHMACAuth("key-1", "shared-secret"))
+	if _, err := client.ListOffers(context.Background()); err != nil {
+		t.Fatalf("ListOffers() error = %v", err)
+	}
+	if gotSignature == "" {
+		t.Errorf("%s header is missing", HeaderSignature)
+	}
+}
+
+func TestWithHMACAuth_SignsQuery(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	defer SetTimeFunc(func() time.Time { return now })()
+
+	var gotRequestURI, gotSignature string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotRequestURI, gotSignature = r.RequestURI, r.Header.Get(HeaderSignature)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`[]`))
+	}))
+	defer ts.Close()
+
+	client := NewAgentClient(ts.URL, WithHMACAuth("key-1", "shared-secret"))
+	if _, err := client.ListSessions(context.Background(), SessionListOptions{Status: SessionStatusActive, OfferID: "offer-1"}); err != nil {
+		t.Fatalf("ListSessions() error = %v", err)
+	}
+
+	if !strings.Contains(gotRequestURI, "?") {
+		t.Fatalf("request URI %q has no query", gotRequestURI)
+	}
+	timestamp := strconv.FormatInt(now.Unix(), 10)
+	if want := hmacSignature([]byte("shared-secret"), http.MethodGet, gotRequestURI, nil, timestamp); gotSignature != want {
+		t.Errorf("%s = %q, want signature over %q", HeaderSignature, gotSignature, gotRequestURI)
+	}
+
+	// Changing a filter in transit breaks the signature
+	tampered := strings.Replace(gotRequestURI, "status=active", "status=closed", 1)
+	if hmacSignature([]byte("shared-secret"), http.MethodGet, tampered, nil, timestamp) == gotSignature {
+		t.Error("signature does not cover the query string")
+	}
+}
@@ -0,0 +1,113 @@
+package atoa
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"testing"
+	"time"
+)
+
+func TestIntrospectToken(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	defer SetTimeFunc(func() time.Time { return now })()
+
+	orgToken, err := IssueOrgToken("test-org", true, testPrivateKey)
+	if err != nil {
+		t.Fatalf("IssueOrgToken() error = %v", err)
+	}
+	agentToken, err := IssueAgentToken(&AgentCard{
+		AgentID:      "agent-1",
+		OrgID:        "test-org",
+		Capabilities: []string{"text"},
+	}, orgToken, testPrivateKey)
+	if err != nil {
+		t.Fatalf("IssueAgentToken() error = %v", err)
+	}
+	customOrgToken, err := IssueOrgToken("test-org", true, testPrivateKey, "custom.audience")
+	if err != nil {
+		t.Fatalf("IssueOrgToken() error = %v", err)
+	}
+
+	otherKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("GenerateKey() error = %v", err)
+	}
+
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("GenerateKey() error = %v", err)
+	}
+	rsaOrgToken, err := IssueOrgToken("test-org", true, rsaKey)
+	if err != nil {
+		t.Fatalf("IssueOrgToken() error = %v", err)
+	}
+
+	tests := []struct {
+		name       string
+		token      string
+		key        crypto.PublicKey
+		wantActive bool
+		wantKind   TokenKind
+	}{
+		{name: "org token", token: orgToken, key: &testPrivateKey.PublicKey, wantActive: true, wantKind: TokenKindOrg},
+		{name: "agent token", token: agentToken, key: &testPrivateKey.PublicKey, wantActive: true, wantKind: TokenKindAgent},
+		{name: "custom audience", token: customOrgToken, key: &testPrivateKey.PublicKey, wantActive: true, wantKind: TokenKindOrg},
+		{name: "RSA org token", token: rsaOrgToken, key: &rsaKey.PublicKey, wantActive: true, wantKind: TokenKindOrg},
+		{name: "wrong key", token: agentToken, key: &otherKey.PublicKey, wantActive: false},
+		{name: "wrong key type", token: agentToken, key: &rsaKey.PublicKey, wantActive: false},
+		{name: "malformed", token: "not.a.token", key: &testPrivateKey.PublicKey, wantActive: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := IntrospectToken(tt.token, tt.key)
+			if err != nil {
+				t.Fatalf("IntrospectToken() error = %v", err)
+			}
+			if got.Active != tt.wantActive {
+				t.Fatalf("Active = %v, want %v (reason %q)", got.Active, tt.wantActive, got.Reason)
+			}
+			if !got.Active {
+				if got.Reason == "" {
+					t.Error("Reason is empty for an inactive token")
+				}
+				if got.Claims != nil {
+					t.Errorf("Claims = %+v, want nil for an inactive token", got.Claims)
+				}
+				return
+			}
+			if got.Kind != tt.wantKind {
+				t.Errorf("Kind = %v, want %v", got.Kind, tt.wantKind)
+			}
+			if want := now.Add(DefaultTokenExpiry); !got.ExpiresAt.Equal(want) {
+				t.Errorf("ExpiresAt = %v, want %v", got.ExpiresAt, want)
+			}
+		})
+	}
+
+	// Claims match the kind
+	got, _ := IntrospectToken(agentToken, &testPrivateKey.PublicKey)
+	if claims, ok := got.Claims.(*AgentTokenClaims); !ok || claims.AgentID != "agent-1" {
+		t.Errorf("Claims = %+v, want *AgentTokenClaims for agent-1", got.Claims)
+	}
+
+	// Expired tokens are inactive
+	now = now.Add(DefaultTokenExpiry + time.Hour)
+	got, _ = IntrospectToken(orgToken, &testPrivateKey.PublicKey)
+	if got.Active || got.Reason == "" {
+		t.Errorf("IntrospectToken() of expired token = %+v, want inactive with reason", got)
+	}
+
+	if _, err := IntrospectToken(orgToken, nil); err == nil {
+		t.Error("IntrospectToken() with nil key error = nil, want error")
+	}
+	if _, err := IntrospectToken(orgToken, (*rsa.PublicKey)(nil)); err == nil {
+		t.Error("IntrospectToken() with nil RSA key error = nil, want error")
+	}
+	if _, err := IntrospectToken(orgToken, "not a key"); err == nil {
+		t.Error("IntrospectToken() with unsupported key error = nil, want error")
+	}
+}
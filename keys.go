@@ -0,0 +1,99 @@
+package atoa
+
+import (
+	"crypto/ecdsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+)
+
+const (
+	// pemTypePublicKey is the PEM block type of PKIX public keys
+	pemTypePublicKey = "PUBLIC KEY"
+	// pemTypePrivateKey is the PEM block type of PKCS #8 private keys
+	pemTypePrivateKey = "PRIVATE KEY"
+	// pemTypeECPrivateKey is the PEM block type of SEC 1 EC private keys
+	pemTypeECPrivateKey = "EC PRIVATE KEY"
+)
+
+// MarshalPublicKeyPEM encodes an ECDSA public key as a PKIX "PUBLIC KEY" PEM
+// block, the format expected in OrgCard.PublicKey
+func MarshalPublicKeyPEM(publicKey *ecdsa.PublicKey) (string, error) {
+	if publicKey == nil {
+		return "", errors.New("public key is required")
+	}
+
+	der, err := x509.MarshalPKIXPublicKey(publicKey)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal public key to DER: %w", err)
+	}
+
+	return string(pem.EncodeToMemory(&pem.Block{Type: pemTypePublicKey, Bytes: der})), nil
+}
+
+// ParsePublicKeyPEM decodes a PKIX "PUBLIC KEY" PEM block holding an ECDSA key
+func ParsePublicKeyPEM(publicKeyPEM string) (*ecdsa.PublicKey, error) {
+	block, _ := pem.Decode([]byte(publicKeyPEM))
+	if block == nil {
+		return nil, errors.New("no PEM block found in public key")
+	}
+	if block.Type != pemTypePublicKey {
+		return nil, fmt.Errorf("unexpected PEM block type %q, want %q", block.Type, pemTypePublicKey)
+	}
+
+	key, err := parsePublicKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("invalid public key DER: %w", err)
+	}
+	if key.ecdsa == nil {
+		return nil, errors.New("public key is not an ECDSA key")
+	}
+
+	return key.ecdsa, nil
+}
+
+// MarshalPrivateKeyPEM encodes an ECDSA private key as a PKCS #8
+// "PRIVATE KEY" PEM block
+func MarshalPrivateKeyPEM(privateKey *ecdsa.PrivateKey) (string, error) {
+	if privateKey == nil {
+		return "", errors.New("private key is required")
+	}
+
+	der, err := x509.MarshalPKCS8PrivateKey(privateKey)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal private key to DER: %w", err)
+	}
+
+	return string(pem.EncodeToMemory(&pem.Block{Type: pemTypePrivateKey, Bytes: der})), nil
+}
+
+// ParsePrivateKeyPEM decodes an ECDSA private key from a PKCS #8
+// "PRIVATE KEY" or SEC 1 "EC PRIVATE KEY" PEM block
+func ParsePrivateKeyPEM(privateKeyPEM string) (*ecdsa.PrivateKey, error) {
+	block, _ := pem.Decode([]byte(privateKeyPEM))
+	if block == nil {
+		return nil, errors.New("no PEM block found in private key")
+	}
+
+	switch block.Type {
+	case pemTypeECPrivateKey:
+		key, err := x509.ParseECPrivateKey(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("invalid EC private key DER: %w", err)
+		}
+		return key, nil
+	case pemTypePrivateKey:
+		key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("invalid PKCS #8 private key DER: %w", err)
+		}
+		ecdsaKey, ok := key.(*ecdsa.PrivateKey)
+		if !ok {
+			return nil, fmt.Errorf("private key is a %T, not an ECDSA key", key)
+		}
+		return ecdsaKey, nil
+	default:
+		return nil, fmt.Errorf("unexpected PEM block type %q, want %q or %q", block.Type, pemTypePrivateKey, pemTypeECPrivateKey)
+	}
+}
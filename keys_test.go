@@ -0,0 +1,107 @@
+package atoa
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"testing"
+)
+
+func TestPublicKeyPEM_Roundtrip(t *testing.T) {
+	privateKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate private key: %v", err)
+	}
+
+	publicKeyPEM, err := MarshalPublicKeyPEM(&privateKey.PublicKey)
+	if err != nil {
+		t.Fatalf("MarshalPublicKeyPEM() error = %v", err)
+	}
+
+	// The encoded key is accepted by OrgCard
+	card := &OrgCard{OrgID: "test-org", Name: "Test Org", Domain: "test.org", PublicKey: publicKeyPEM}
+	if err := card.Validate(); err != nil {
+		t.Errorf("OrgCard.Validate() error = %v", err)
+	}
+
+	publicKey, err := ParsePublicKeyPEM(publicKeyPEM)
+	if err != nil {
+		t.Fatalf("ParsePublicKeyPEM() error = %v", err)
+	}
+	if !publicKey.Equal(&privateKey.PublicKey) {
+		t.Error("ParsePublicKeyPEM() returned a different key")
+	}
+}
+
+func TestPrivateKeyPEM_Roundtrip(t *testing.T) {
+	privateKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate private key: %v", err)
+	}
+
+	privateKeyPEM, err := MarshalPrivateKeyPEM(privateKey)
+	if err != nil {
+		t.Fatalf("MarshalPrivateKeyPEM() error = %v", err)
+	}
+	parsed, err := ParsePrivateKeyPEM(privateKeyPEM)
+	if err != nil {
+		t.Fatalf("ParsePrivateKeyPEM() error = %v", err)
+	}
+	if !parsed.Equal(privateKey) {
+		t.Error("ParsePrivateKeyPEM() returned a different key")
+	}
+
+	// SEC 1 encoded keys are accepted too
+	der, err := x509.MarshalECPrivateKey(privateKey)
+	if err != nil {
+		t.Fatalf("failed to marshal EC private key: %v", err)
+	}
+	sec1PEM := string(pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: der}))
+	if _, err := ParsePrivateKeyPEM(sec1PEM); err != nil {
+		t.Errorf("ParsePrivateKeyPEM() with SEC 1 key error = %v", err)
+	}
+}
+
+func TestParsePublicKeyPEM_Errors(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("failed to generate RSA key: %v", err)
+	}
+	rsaDER, err := x509.MarshalPKIXPublicKey(&rsaKey.PublicKey)
+	if err != nil {
+		t.Fatalf("failed to marshal RSA key: %v", err)
+	}
+
+	tests := []struct {
+		name string
+		pem  string
+	}{
+		{
+			name: "not PEM",
+			pem:  "invalid-key",
+		},
+		{
+			name: "wrong block type",
+			pem:  string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: rsaDER})),
+		},
+		{
+			name: "invalid DER",
+			pem:  string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: []byte("garbage")})),
+		},
+		{
+			name: "RSA key",
+			pem:  string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: rsaDER})),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := ParsePublicKeyPEM(tt.pem); err == nil {
+				t.Error("ParsePublicKeyPEM() error = nil, want error")
+			}
+		})
+	}
+}
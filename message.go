@@ -10,78 +10,282 @@ import (
 	"time"
 )
 
+// DefaultMaxPayloadBytes is the payload size limit used when
+// AgentClient.MaxPayloadBytes is not set
+const DefaultMaxPayloadBytes = 1 << 20
+
+// MessageType identifies the kind of payload carried by an A2AMessage
+type MessageType string
+
+const (
+	// MessageTypeText is a plain text message with a {"content": "..."} payload
+	MessageTypeText MessageType = "text"
+	// MessageTypeForm is a structured form to be filled in by the recipient
+	MessageTypeForm MessageType = "form"
+	// MessageTypeData is an arbitrary JSON data payload
+	MessageTypeData MessageType = "data"
+	// MessageTypeFile is a file reference or inline file content
+	MessageTypeFile MessageType = "file"
+)
+
+// IsKnown reports whether the type is one of the built-in message types.
+// Clients send other types only with AgentClient.AllowCustomMessageTypes.
+func (t MessageType) IsKnown() bool {
+	switch t {
+	case MessageTypeText, MessageTypeForm, MessageTypeData, MessageTypeFile:
+		return true
+	default:
+		return false
+	}
+}
+
 // A2AMessage represents a message sent between agents in a session
 type A2AMessage struct {
 	SessionID   string          `json:"session_id"`
 	FromAgentID string          `json:"from_agent_id"`
 	ToAgentID   string          `json:"to_agent_id"`
-	Type        string          `json:"type"`
+	Type        MessageType     `json:"type"`
 	Payload     json.RawMessage `json:"payload"`
 	Timestamp   time.Time       `json:"timestamp"`
+
+	// Seq numbers the messages a client sends in a session, starting at 1,
+	// when the client has OrderMessages set. Delivery order is not
+	// guaranteed, so receivers that need ordering should buffer messages per
+	// session and hand them on in Seq order, holding back any message until
+	// the one before it has arrived. A gap that never fills means a lost
+	// message. Zero means the message is unordered.
+	Seq uint64 `json:"seq,omitempty"`
+
+	// Encrypted marks a payload that holds an EncryptPayload envelope rather
+	// than the plain message content
+	Encrypted bool `json:"encrypted,omitempty"`
 }
 
-// Validate checks if all required fields are present in the message
+// Validate checks if all required fields are present in the message and that
+// its type is one of the built-in message types
 func (m *A2AMessage) Validate() error {
+	return m.validationErrors(false).err()
+}
+
+// ValidateAllowingCustom is like Validate but accepts any non-empty type, for
+// receivers that handle custom message types
+func (m *A2AMessage) ValidateAllowingCustom() error {
+	return m.validationErrors(true).err()
+}
+
+// ValidateOrdered is like Validate for clients in ordering mode, and
+// additionally requires a positive Seq
+func (m *A2AMessage) ValidateOrdered() error {
+	errs := m.validationErrors(false)
+	if m.Seq == 0 {
+		errs.add("seq", "must be positive in ordering mode")
+	}
+	return errs.err()
+}
+
+// validationErrors collects every problem with the message fields. Types
+// that are not built in are problems unless allowCustom is set.
+func (m *A2AMessage) validationErrors(allowCustom bool) ValidationErrors {
+	var errs ValidationErrors
 	if m.SessionID == "" {
-		return fmt.Errorf("session_id is required")
+		errs.add("session_id", "is required")
 	}
 	if m.FromAgentID == "" {
-		return fmt.Errorf("from_agent_id is required")
+		errs.add("from_agent_id", "is required")
 	}
 	if m.ToAgentID == "" {
-		return fmt.Errorf("to_agent_id is required")
+		errs.add("to_agent_id", "is required")
 	}
 	if m.Type == "" {
-		return fmt.Errorf("type is required")
+		errs.add("type", "is required")
+	} else if !allowCustom && !m.Type.IsKnown() {
+		errs.add("type", fmt.Sprintf("%q is not a known message type", m.Type))
 	}
 	if m.Payload == nil {
-		return fmt.Errorf("payload is required")
+		errs.add("payload", "is required")
 	}
 	if m.Timestamp.IsZero() {
-		return fmt.Errorf("timestamp is required")
+		errs.add("timestamp", "is required")
 	}
-	return nil
+	return errs
+}
+
+// TextPayload returns the content of a text message payload
+func (m *A2AMessage) TextPayload() (string, error) {
+	if m.Type != MessageTypeText {
+		return "", fmt.Errorf("message type is %q, not %q", m.Type, MessageTypeText)
+	}
+
+	var payload struct {
+		Content *string `json:"content"`
+	}
+	if err := json.Unmarshal(m.Payload, &payload); err != nil {
+		return "", fmt.Errorf("failed to decode text payload: %w", err)
+	}
+	if payload.Content == nil {
+		return "", fmt.Errorf("text payload has no content")
+	}
+
+	return *payload.Content, nil
+}
+
+// MessageReceipt is the platform's acknowledgement of an accepted message
+type MessageReceipt struct {
+	MessageID  string    `json:"message_id"`
+	AcceptedAt time.Time `json:"accepted_at"`
+	Status     string    `json:"status"`
 }
 
 // SendMessage sends an A2A message to a session
 func (c *AgentClient) SendMessage(ctx context.Context, msg A2AMessage) error {
-	// Validate message fields
-	if err := msg.Validate(); err != nil {
-		return fmt.Errorf("invalid message: %w", err)
+	_, err := c.sendMessage(ctx, msg, false)
+	return err
+}
+
+// SendMessageWithReceipt sends an A2A message to a session and returns the
+// delivery receipt from the response body
+func (c *AgentClient) SendMessageWithReceipt(ctx context.Context, msg A2AMessage) (*MessageReceipt, error) {
+	return c.sendMessage(ctx, msg, true)
+}
+
+// sendMessage sends msg and decodes the receipt when wantReceipt is set, so
+// that SendMessage keeps working against servers that reply with no body
+func (c *AgentClient) sendMessage(ctx context.Context, msg A2AMessage, wantReceipt bool) (*MessageReceipt, error) {
+	// Validate message fields. In ordering mode a message without a Seq is
+	// numbered only once every local check has passed, so that rejected
+	// messages do not leave gaps in the session.
+	number := c.OrderMessages && msg.Seq == 0
+	if err := msg.validationErrors(c.AllowCustomMessageTypes).err(); err != nil {
+		return nil, fmt.Errorf("invalid message: %w", err)
 	}
 
-	// Create request
-	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.BaseURL+"/messages", nil)
+	// Check payload size before doing any more work
+	maxPayload := c.MaxPayloadBytes
+	if maxPayload <= 0 {
+		maxPayload = DefaultMaxPayloadBytes
+	}
+	if len(msg.Payload) > maxPayload {
+		return nil, fmt.Errorf("%w: %d bytes exceeds limit of %d bytes", ErrPayloadTooLarge, len(msg.Payload), maxPayload)
+	}
+
+	// Check the payload against its schema, if one is configured
+	if c.PayloadValidator != nil {
+		if err := msg.ValidatePayload(c.PayloadValidator); err != nil {
+			return nil, fmt.Errorf("invalid message: %w", err)
+		}
+	}
+
+	// Fail fast for unverified agents
+	if c.RequireVerified {
+		if err := c.checkVerified(requireVerified); err != nil {
+			return nil, err
+		}
+	}
+
+	// Encrypt the payload to the recipient, once it has passed validation
+	if c.RecipientKey != nil && !msg.Encrypted {
+		publicKeyPEM, err := c.RecipientKey(ctx, msg.ToAgentID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to look up recipient key: %w", err)
+		}
+		payload, err := EncryptPayload(msg.Payload, publicKeyPEM)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encrypt payload: %w", err)
+		}
+		// The envelope is larger than the plaintext, and it is what is sent
+		if len(payload) > maxPayload {
+			return nil, fmt.Errorf("%w: encrypted payload of %d bytes exceeds limit of %d bytes", ErrPayloadTooLarge, len(payload), maxPayload)
+		}
+		msg.Payload = payload
+		msg.Encrypted = true
+	}
+
+	ctx, done, err := c.beginRequest(ctx)
 	if err != nil {
-		return fmt.Errorf("failed to create request: %w", err)
+		return nil, err
 	}
+	defer done()
 
-	// Set authorization header
-	if c.Token != "" {
-		req.Header.Set("Authorization", "Bearer "+c.Token)
+	if number {
+		msg.Seq = c.NextSeq(msg.SessionID)
 	}
 
-	// Set content type
-	req.Header.Set("Content-Type", "application/json")
+	// Encode the message with the client's codec
+	codec := c.codec()
+	body, err := codec.Marshal(msg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal message: %w", err)
+	}
 
-	// Marshal message to JSON
-	body, err := json.Marshal(msg)
+	// Create the request with its body so that GetBody is set for redirects
+	// and retries
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.endpoint("/messages"), bytes.NewReader(body))
 	if err != nil {
-		return fmt.Errorf("failed to marshal message: %w", err)
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	c.setHeaders(req)
+
+	// Set authorization header
+	if token := c.Token(); token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
 	}
-	req.Body = io.NopCloser(bytes.NewReader(body))
+
+	// Ask for the receipt in the same encoding
+	req.Header.Set("Content-Type", codec.ContentType())
+	acceptCodec(req, codec)
+	acceptGzip(req)
 
 	// Send request
-	resp, err := c.HTTP.Do(req)
+	resp, err := doCompressed(c.HTTP, req, body, c.CompressionThreshold)
 	if err != nil {
-		return fmt.Errorf("failed to send request: %w", err)
+		return nil, fmt.Errorf("failed to send request: %w", err)
 	}
 	defer resp.Body.Close()
 
-	// Check response status
-	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	// Check response status; receipt endpoints may answer 201 or 202
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return nil, newAPIError(resp)
+	}
+
+	if !wantReceipt {
+		return nil, nil
+	}
+
+	var receipt MessageReceipt
+	if err := c.decodeReceipt(resp, codec, &receipt); err != nil {
+		return nil, fmt.Errorf("failed to decode receipt: %w", err)
+	}
+
+	return &receipt, nil
+}
+
+// NextSeq reserves the next sequence number for a session. SendMessage uses
+// it for messages without a Seq in ordering mode; a send that fails after that
+// point, for example on the network, leaves a gap. Callers that need to retry
+// without a gap can number the message with NextSeq themselves and resend it
+// with the same Seq.
+func (c *AgentClient) NextSeq(sessionID string) uint64 {
+	c.seqMu.Lock()
+	defer c.seqMu.Unlock()
+	if c.seqs == nil {
+		c.seqs = make(map[string]uint64)
 	}
+	c.seqs[sessionID]++
+	return c.seqs[sessionID]
+}
 
-	return nil
+// decodeReceipt decodes a message receipt with codec when the server answered
+// in its content type, and like any other response otherwise
+func (c *AgentClient) decodeReceipt(resp *http.Response, codec Codec, receipt *MessageReceipt) error {
+	if c.ResponseDecoder != nil || !hasContentType(resp, codec.ContentType()) {
+		return decodeResponse(c.ResponseDecoder, resp, receipt)
+	}
+	if err := decompressResponse(resp); err != nil {
+		return err
+	}
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	return codec.Unmarshal(data, receipt)
 }
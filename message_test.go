@@ -3,8 +3,13 @@ package atoa
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"net/http"
 	"net/http/httptest"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 )
@@ -49,6 +54,7 @@ func TestSendMessage(t *testing.T) {
 	tests := []struct {
 		name          string
 		client        *AgentClient
+		token         string
 		message       A2AMessage
 		expectedError bool
 	}{
@@ -56,9 +62,9 @@ func TestSendMessage(t *testing.T) {
 			name: "verified agent sends valid message",
 			client: &AgentClient{
 				BaseURL: server.URL,
-				Token:   "valid-token",
 				HTTP:    &http.Client{},
 			},
+			token: "valid-token",
 			message: A2AMessage{
 				SessionID:   "session-123",
 				FromAgentID: "agent-1",
@@ -73,9 +79,9 @@ func TestSendMessage(t *testing.T) {
 			name: "unverified agent sends message",
 			client: &AgentClient{
 				BaseURL: server.URL,
-				Token:   "invalid-token",
 				HTTP:    &http.Client{},
 			},
+			token: "invalid-token",
 			message: A2AMessage{
 				SessionID:   "session-123",
 				FromAgentID: "agent-1",
@@ -90,9 +96,9 @@ func TestSendMessage(t *testing.T) {
 			name: "message with missing fields",
 			client: &AgentClient{
 				BaseURL: server.URL,
-				Token:   "valid-token",
 				HTTP:    &http.Client{},
 			},
+			token: "valid-token",
 			message: A2AMessage{
 				SessionID: "session-123",
 				// Missing required fields
@@ -104,6 +110,7 @@ func TestSendMessage(t *testing.T) {
 	// Run test cases
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
+			tt.client.SetToken(tt.token)
 			err := tt.client.SendMessage(context.Background(), tt.message)
 			if tt.expectedError && err == nil {
 				t.Errorf("Expected error but got none")
@@ -114,3 +121,482 @@ func TestSendMessage(t *testing.T) {
 		})
 	}
 }
+
+func TestSendMessage_RequireVerified(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name         string
+		verified     bool
+		wantErr      error
+		wantRequests int
+	}{
+		{
+			name:         "verified agent",
+			verified:     true,
+			wantErr:      nil,
+			wantRequests: 1,
+		},
+		{
+			name:         "unverified agent",
+			verified:     false,
+			wantErr:      ErrAgentNotVerified,
+			wantRequests: 0,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			requests = 0
+			client := &AgentClient{
+				BaseURL:         server.URL,
+				HTTP:            &http.Client{},
+				RequireVerified: true,
+			}
+			client.SetToken(issueTestAgentToken(t, tt.verified))
+			msg := A2AMessage{
+				SessionID:   "session-123",
+				FromAgentID: "agent-1",
+				ToAgentID:   "agent-2",
+				Type:        "text",
+				Payload:     json.RawMessage(`{"content": "Hello"}`),
+				Timestamp:   time.Now(),
+			}
+
+			err := client.SendMessage(context.Background(), msg)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("SendMessage() error = %v, want %v", err, tt.wantErr)
+			}
+			if requests != tt.wantRequests {
+				t.Errorf("server received %d requests, want %d", requests, tt.wantRequests)
+			}
+		})
+	}
+}
+
+// Helper function to issue an agent token signed with testPrivateKey
+func issueTestAgentToken(t *testing.T, verified bool) string {
+	orgToken, err := IssueOrgToken("test-org", verified, testPrivateKey)
+	if err != nil {
+		t.Fatalf("failed to issue org token: %v", err)
+	}
+
+	card := &AgentCard{
+		AgentID:      "agent-1",
+		OrgID:        "test-org",
+		Capabilities: []string{"text"},
+	}
+	token, err := IssueAgentToken(card, orgToken, testPrivateKey)
+	if err != nil {
+		t.Fatalf("failed to issue agent token: %v", err)
+	}
+
+	return token
+}
+
+func TestA2AMessage_ValidateType(t *testing.T) {
+	tests := []struct {
+		name    string
+		msgType MessageType
+		wantErr bool
+	}{
+		{name: "text", msgType: MessageTypeText, wantErr: false},
+		{name: "data", msgType: MessageTypeData, wantErr: false},
+		{name: "custom type", msgType: "x-custom", wantErr: true},
+		{name: "unknown type", msgType: "txet", wantErr: true},
+		{name: "empty type", msgType: "", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			msg := A2AMessage{
+				SessionID:   "session-123",
+				FromAgentID: "agent-1",
+				ToAgentID:   "agent-2",
+				Type:        tt.msgType,
+				Payload:     json.RawMessage(`{}`),
+				Timestamp:   time.Now(),
+			}
+			err := msg.Validate()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestSendMessage_AllowCustomMessageTypes(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	msg := A2AMessage{
+		SessionID:   "session-123",
+		FromAgentID: "agent-1",
+		ToAgentID:   "agent-2",
+		Type:        "x-custom",
+		Payload:     json.RawMessage(`{}`),
+		Timestamp:   time.Now(),
+	}
+
+	client := &AgentClient{BaseURL: server.URL, HTTP: &http.Client{}}
+	var errs ValidationErrors
+	if err := client.SendMessage(context.Background(), msg); !errors.As(err, &errs) {
+		t.Errorf("SendMessage() error = %v, want ValidationErrors", err)
+	}
+
+	// The setting is per client; other clients still reject the type
+	custom := &AgentClient{BaseURL: server.URL, HTTP: &http.Client{}, AllowCustomMessageTypes: true}
+	if err := custom.SendMessage(context.Background(), msg); err != nil {
+		t.Errorf("SendMessage() with AllowCustomMessageTypes error = %v", err)
+	}
+	if requests != 1 {
+		t.Errorf("server received %d requests, want 1", requests)
+	}
+
+	// An empty type is still rejected
+	msg.Type = ""
+	if err := custom.SendMessage(context.Background(), msg); err == nil {
+		t.Error("SendMessage() with empty type error = nil, want error")
+	}
+}
+
+func TestA2AMessage_ValidateAllowingCustom(t *testing.T) {
+	msg := A2AMessage{
+		SessionID:   "session-123",
+		FromAgentID: "agent-1",
+		ToAgentID:   "agent-2",
+		Type:        "x-custom",
+		Payload:     json.RawMessage(`{}`),
+		Timestamp:   time.Now(),
+	}
+	if err := msg.Validate(); err == nil {
+		t.Error("Validate() with custom type error = nil, want error")
+	}
+	if err := msg.ValidateAllowingCustom(); err != nil {
+		t.Errorf("ValidateAllowingCustom() error = %v", err)
+	}
+
+	msg.Type = ""
+	if err := msg.ValidateAllowingCustom(); err == nil {
+		t.Error("ValidateAllowingCustom() with empty type error = nil, want error")
+	}
+}
+
+func TestA2AMessage_TextPayload(t *testing.T) {
+	msg := A2AMessage{Type: MessageTypeText, Payload: json.RawMessage(`{"content": "Hello"}`)}
+	text, err := msg.TextPayload()
+	if err != nil {
+		t.Fatalf("TextPayload() error = %v", err)
+	}
+	if text != "Hello" {
+		t.Errorf("TextPayload() = %q, want %q", text, "Hello")
+	}
+
+	msg = A2AMessage{Type: MessageTypeData, Payload: json.RawMessage(`{"content": "Hello"}`)}
+	if _, err := msg.TextPayload(); err == nil {
+		t.Error("TextPayload() on data message error = nil, want error")
+	}
+
+	msg = A2AMessage{Type: MessageTypeText, Payload: json.RawMessage(`{"text": "Hello"}`)}
+	if _, err := msg.TextPayload(); err == nil {
+		t.Error("TextPayload() without content error = nil, want error")
+	}
+}
+
+func TestSendMessage_PayloadTooLarge(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	client := &AgentClient{
+		BaseURL:         server.URL,
+		HTTP:            &http.Client{},
+		MaxPayloadBytes: 16,
+	}
+	client.SetToken("valid-token")
+	msg := A2AMessage{
+		SessionID:   "session-123",
+		FromAgentID: "agent-1",
+		ToAgentID:   "agent-2",
+		Type:        MessageTypeText,
+		Payload:     json.RawMessage(`{"content": "more than sixteen bytes"}`),
+		Timestamp:   time.Now(),
+	}
+
+	err := client.SendMessage(context.Background(), msg)
+	if !errors.Is(err, ErrPayloadTooLarge) {
+		t.Errorf("SendMessage() error = %v, want %v", err, ErrPayloadTooLarge)
+	}
+	if requests != 0 {
+		t.Errorf("server received %d requests, want 0", requests)
+	}
+
+	// The default limit accepts ordinary payloads
+	client.MaxPayloadBytes = 0
+	if err := client.SendMessage(context.Background(), msg); err != nil {
+		t.Errorf("SendMessage() with default limit error = %v", err)
+	}
+}
+
+func TestSendMessageWithReceipt(t *testing.T) {
+	tests := []struct {
+		name   string
+		status int
+	}{
+		{"ok", http.StatusOK},
+		{"created", http.StatusCreated},
+		{"accepted", http.StatusAccepted},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(tt.status)
+				w.Write([]byte(`{"message_id": "message-1", "accepted_at": "2024-03-20T12:00:00Z", "status": "accepted"}`))
+			}))
+			defer server.Close()
+
+			client := &AgentClient{
+				BaseURL: server.URL,
+				HTTP:    &http.Client{},
+			}
+			client.SetToken("valid-token")
+			msg := A2AMessage{
+				SessionID:   "session-123",
+				FromAgentID: "agent-1",
+				ToAgentID:   "agent-2",
+				Type:        MessageTypeText,
+				Payload:     json.RawMessage(`{"content": "Hello"}`),
+				Timestamp:   time.Now(),
+			}
+
+			receipt, err := client.SendMessageWithReceipt(context.Background(), msg)
+			if err != nil {
+				t.Fatalf("SendMessageWithReceipt() error = %v", err)
+			}
+			if receipt.MessageID != "message-1" {
+				t.Errorf("MessageID = %v, want %v", receipt.MessageID, "message-1")
+			}
+			if receipt.Status != "accepted" {
+				t.Errorf("Status = %v, want %v", receipt.Status, "accepted")
+			}
+			if want := time.Date(2024, 3, 20, 12, 0, 0, 0, time.UTC); !receipt.AcceptedAt.Equal(want) {
+				t.Errorf("AcceptedAt = %v, want %v", receipt.AcceptedAt, want)
+			}
+		})
+	}
+}
+
+func TestSendMessage_OrderMessages(t *testing.T) {
+	var mu sync.Mutex
+	seqs := make(map[string][]uint64)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var msg A2AMessage
+		if err := json.NewDecoder(r.Body).Decode(&msg); err != nil {
+			t.Errorf("failed to decode message: %v", err)
+		}
+		mu.Lock()
+		seqs[msg.SessionID] = append(seqs[msg.SessionID], msg.Seq)
+		mu.Unlock()
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	client := &AgentClient{
+		BaseURL:       server.URL,
+		HTTP:          &http.Client{},
+		OrderMessages: true,
+	}
+
+	const perSession = 10
+	var wg sync.WaitGroup
+	for _, sessionID := range []string{"session-1", "session-2"} {
+		for i := 0; i < perSession; i++ {
+			wg.Add(1)
+			go func(sessionID string) {
+				defer wg.Done()
+				err := client.SendMessage(context.Background(), A2AMessage{
+					SessionID:   sessionID,
+					FromAgentID: "agent-1",
+					ToAgentID:   "agent-2",
+					Type:        MessageTypeText,
+					Payload:     json.RawMessage(`{"content": "Hello"}`),
+					Timestamp:   time.Now(),
+				})
+				if err != nil {
+					t.Errorf("SendMessage() error = %v", err)
+				}
+			}(sessionID)
+		}
+	}
+	wg.Wait()
+
+	// Each session gets the sequence numbers 1..perSession exactly once,
+	// though possibly delivered out of order
+	for sessionID, got := range seqs {
+		sort.Slice(got, func(i, j int) bool { return got[i] < got[j] })
+		for i, seq := range got {
+			if seq != uint64(i+1) {
+				t.Errorf("%s seqs = %v, want 1..%d", sessionID, got, perSession)
+				break
+			}
+		}
+	}
+	if len(seqs) != 2 {
+		t.Errorf("messages received for %d sessions, want 2", len(seqs))
+	}
+}
+
+func TestSendMessage_OrderMessagesRejectedSend(t *testing.T) {
+	var seqs []uint64
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var msg A2AMessage
+		if err := json.NewDecoder(r.Body).Decode(&msg); err != nil {
+			t.Errorf("failed to decode message: %v", err)
+		}
+		seqs = append(seqs, msg.Seq)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	client := &AgentClient{
+		BaseURL:         server.URL,
+		HTTP:            &http.Client{},
+		OrderMessages:   true,
+		MaxPayloadBytes: 64,
+	}
+	send := func(payload string) error {
+		return client.SendMessage(context.Background(), A2AMessage{
+			SessionID:   "session-1",
+			FromAgentID: "agent-1",
+			ToAgentID:   "agent-2",
+			Type:        MessageTypeText,
+			Payload:     json.RawMessage(payload),
+			Timestamp:   time.Now(),
+		})
+	}
+
+	if err := send(`{"content": "first"}`); err != nil {
+		t.Fatalf("SendMessage() error = %v", err)
+	}
+	if err := send(`{"content": "` + strings.Repeat("x", 100) + `"}`); !errors.Is(err, ErrPayloadTooLarge) {
+		t.Fatalf("SendMessage() error = %v, want %v", err, ErrPayloadTooLarge)
+	}
+	if err := send(`{"content": "second"}`); err != nil {
+		t.Fatalf("SendMessage() error = %v", err)
+	}
+
+	if !reflect.DeepEqual(seqs, []uint64{1, 2}) {
+		t.Errorf("seqs = %v, want [1 2]", seqs)
+	}
+}
+
+func TestA2AMessage_ValidateOrdered(t *testing.T) {
+	msg := A2AMessage{
+		SessionID:   "session-123",
+		FromAgentID: "agent-1",
+		ToAgentID:   "agent-2",
+		Type:        MessageTypeText,
+		Payload:     json.RawMessage(`{}`),
+		Timestamp:   time.Now(),
+	}
+	if err := msg.ValidateOrdered(); err == nil {
+		t.Error("ValidateOrdered() without seq error = nil, want error")
+	}
+
+	msg.Seq = 1
+	if err := msg.ValidateOrdered(); err != nil {
+		t.Errorf("ValidateOrdered() error = %v", err)
+	}
+}
+
+func TestSendMessage_CancelMidFlight(t *testing.T) {
+	arrived := make(chan struct{})
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(arrived)
+		select {
+		case <-r.Context().Done():
+		case <-release:
+		}
+	}))
+	defer server.Close()
+	defer close(release)
+
+	client := &AgentClient{
+		BaseURL: server.URL,
+		HTTP:    &http.Client{},
+	}
+	client.SetToken("valid-token")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		<-arrived
+		cancel()
+	}()
+
+	start := time.Now()
+	err := client.SendMessage(ctx, A2AMessage{
+		SessionID:   "session-123",
+		FromAgentID: "agent-1",
+		ToAgentID:   "agent-2",
+		Type:        MessageTypeText,
+		Payload:     json.RawMessage(`{"content": "Hello"}`),
+		Timestamp:   time.Now(),
+	})
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("SendMessage() error = %v, want %v", err, context.Canceled)
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("SendMessage() returned after %v, want prompt abort", elapsed)
+	}
+}
+
+func TestSendMessage_FollowsRedirectWithBody(t *testing.T) {
+	var received A2AMessage
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/messages" {
+			http.Redirect(w, r, "/v2/messages", http.StatusTemporaryRedirect)
+			return
+		}
+		if err := json.NewDecoder(r.Body).Decode(&received); err != nil {
+			t.Errorf("redirected request has no message body: %v", err)
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	client := &AgentClient{
+		BaseURL: server.URL,
+		HTTP:    &http.Client{},
+	}
+	client.SetToken("valid-token")
+
+	err := client.SendMessage(context.Background(), A2AMessage{
+		SessionID:   "session-123",
+		FromAgentID: "agent-1",
+		ToAgentID:   "agent-2",
+		Type:        MessageTypeText,
+		Payload:     json.RawMessage(`{"content": "Hello"}`),
+		Timestamp:   time.Now(),
+	})
+	if err != nil {
+		t.Fatalf("SendMessage() error = %v", err)
+	}
+	if received.SessionID != "session-123" {
+		t.Errorf("redirected SessionID = %q, want %q", received.SessionID, "session-123")
+	}
+}
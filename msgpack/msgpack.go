@@ -0,0 +1,415 @@
+// Package msgpack provides a MessagePack Codec for atoa A2A messages.
+//
+// Values are mapped through their JSON form, so struct tags, custom JSON
+// marshalers and json.RawMessage payloads behave exactly as with the default
+// JSON codec: a message payload is sent as a native MessagePack map rather
+// than an embedded JSON string, and decodes back into an equivalent compact
+// json.RawMessage with sorted object keys. Only the types JSON can express
+// are produced; MessagePack binary values are decoded as strings and
+// extension types are rejected. Input nested deeper than MaxDepth is
+// rejected as well.
+package msgpack
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+)
+
+// ContentType is the media type of MessagePack bodies
+const ContentType = "application/msgpack"
+
+// Codec encodes values as MessagePack. The zero value is ready to use.
+type Codec struct{}
+
+// ContentType returns ContentType
+func (Codec) ContentType() string {
+	return ContentType
+}
+
+// Marshal returns the MessagePack encoding of v
+func (Codec) Marshal(v interface{}) ([]byte, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var tree interface{}
+	if err := decoder.Decode(&tree); err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	if err := encode(&buf, tree); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// Unmarshal decodes MessagePack data into v
+func (Codec) Unmarshal(data []byte, v interface{}) error {
+	d := &decoder{data: data}
+	tree, err := d.decode()
+	if err != nil {
+		return err
+	}
+	if d.pos != len(d.data) {
+		return fmt.Errorf("msgpack: %d trailing bytes", len(d.data)-d.pos)
+	}
+
+	jsonData, err := json.Marshal(tree)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(jsonData, v)
+}
+
+// encode writes a value produced by a json.Decoder with UseNumber
+func encode(buf *bytes.Buffer, v interface{}) error {
+	switch v := v.(type) {
+	case nil:
+		buf.WriteByte(0xc0)
+	case bool:
+		if v {
+			buf.WriteByte(0xc3)
+		} else {
+			buf.WriteByte(0xc2)
+		}
+	case json.Number:
+		return encodeNumber(buf, v)
+	case string:
+		encodeString(buf, v)
+	case []interface{}:
+		writeHeader(buf, len(v), 0x90, 15, 0xdc, 0xdd)
+		for _, item := range v {
+			if err := encode(buf, item); err != nil {
+				return err
+			}
+		}
+	case map[string]interface{}:
+		// Sorted keys keep the encoding deterministic
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		writeHeader(buf, len(keys), 0x80, 15, 0xde, 0xdf)
+		for _, key := range keys {
+			encodeString(buf, key)
+			if err := encode(buf, v[key]); err != nil {
+				return err
+			}
+		}
+	default:
+		return fmt.Errorf("msgpack: unsupported type %T", v)
+	}
+	return nil
+}
+
+// encodeNumber writes integers in their smallest form and other numbers as
+// float64
+func encodeNumber(buf *bytes.Buffer, n json.Number) error {
+	if i, err := strconv.ParseInt(string(n), 10, 64); err == nil {
+		encodeInt(buf, i)
+		return nil
+	}
+	if u, err := strconv.ParseUint(string(n), 10, 64); err == nil {
+		buf.WriteByte(0xcf)
+		binary.Write(buf, binary.BigEndian, u)
+		return nil
+	}
+	f, err := n.Float64()
+	if err != nil {
+		return fmt.Errorf("msgpack: invalid number %q", n)
+	}
+	buf.WriteByte(0xcb)
+	binary.Write(buf, binary.BigEndian, math.Float64bits(f))
+	return nil
+}
+
+// encodeInt writes a signed integer in its smallest form
+func encodeInt(buf *bytes.Buffer, i int64) {
+	switch {
+	case i >= 0 && i <= 0x7f:
+		buf.WriteByte(byte(i))
+	case i >= -32 && i < 0:
+		buf.WriteByte(byte(i))
+	case i >= 0 && i <= math.MaxUint8:
+		buf.Write([]byte{0xcc, byte(i)})
+	case i >= 0 && i <= math.MaxUint16:
+		buf.WriteByte(0xcd)
+		binary.Write(buf, binary.BigEndian, uint16(i))
+	case i >= 0 && i <= math.MaxUint32:
+		buf.WriteByte(0xce)
+		binary.Write(buf, binary.BigEndian, uint32(i))
+	case i >= 0:
+		buf.WriteByte(0xcf)
+		binary.Write(buf, binary.BigEndian, uint64(i))
+	case i >= math.MinInt8:
+		buf.Write([]byte{0xd0, byte(int8(i))})
+	case i >= math.MinInt16:
+		buf.WriteByte(0xd1)
+		binary.Write(buf, binary.BigEndian, int16(i))
+	case i >= math.MinInt32:
+		buf.WriteByte(0xd2)
+		binary.Write(buf, binary.BigEndian, int32(i))
+	default:
+		buf.WriteByte(0xd3)
+		binary.Write(buf, binary.BigEndian, i)
+	}
+}
+
+// encodeString writes a UTF-8 string
+func encodeString(buf *bytes.Buffer, s string) {
+	if len(s) <= math.MaxUint8 && len(s) > 31 {
+		buf.Write([]byte{0xd9, byte(len(s))})
+	} else {
+		writeHeader(buf, len(s), 0xa0, 31, 0xda, 0xdb)
+	}
+	buf.WriteString(s)
+}
+
+// writeHeader writes the length prefix of a string, array or map: a fix
+// format up to fixMax, then the 16-bit and 32-bit formats
+func writeHeader(buf *bytes.Buffer, n int, fix byte, fixMax int, code16, code32 byte) {
+	switch {
+	case n <= fixMax:
+		buf.WriteByte(fix | byte(n))
+	case n <= math.MaxUint16:
+		buf.WriteByte(code16)
+		binary.Write(buf, binary.BigEndian, uint16(n))
+	default:
+		buf.WriteByte(code32)
+		binary.Write(buf, binary.BigEndian, uint32(n))
+	}
+}
+
+// MaxDepth is the deepest nesting of arrays and maps Unmarshal accepts, the
+// same limit encoding/json applies
+const MaxDepth = 10000
+
+// errShortData is returned when the input ends inside a value
+var errShortData = errors.New("msgpack: unexpected end of data")
+
+// errTooDeep is returned for input nested deeper than MaxDepth
+var errTooDeep = fmt.Errorf("msgpack: exceeded max depth of %d", MaxDepth)
+
+// decoder reads MessagePack values into the types json.Marshal accepts
+type decoder struct {
+	data  []byte
+	pos   int
+	depth int
+}
+
+// decode reads one value
+func (d *decoder) decode() (interface{}, error) {
+	code, err := d.byte()
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case code <= 0x7f:
+		return json.Number(strconv.Itoa(int(code))), nil
+	case code >= 0xe0:
+		return json.Number(strconv.Itoa(int(int8(code)))), nil
+	case code&0xf0 == 0x80:
+		return d.decodeMap(int(code & 0x0f))
+	case code&0xf0 == 0x90:
+		return d.decodeArray(int(code & 0x0f))
+	case code&0xe0 == 0xa0:
+		return d.decodeString(int(code & 0x1f))
+	}
+
+	switch code {
+	case 0xc0:
+		return nil, nil
+	case 0xc2:
+		return false, nil
+	case 0xc3:
+		return true, nil
+	case 0xc4, 0xd9:
+		n, err := d.length(1)
+		if err != nil {
+			return nil, err
+		}
+		return d.decodeString(n)
+	case 0xc5, 0xda:
+		n, err := d.length(2)
+		if err != nil {
+			return nil, err
+		}
+		return d.decodeString(n)
+	case 0xc6, 0xdb:
+		n, err := d.length(4)
+		if err != nil {
+			return nil, err
+		}
+		return d.decodeString(n)
+	case 0xca:
+		bits, err := d.uint(4)
+		if err != nil {
+			return nil, err
+		}
+		return floatNumber(float64(math.Float32frombits(uint32(bits))))
+	case 0xcb:
+		bits, err := d.uint(8)
+		if err != nil {
+			return nil, err
+		}
+		return floatNumber(math.Float64frombits(bits))
+	case 0xcc, 0xcd, 0xce, 0xcf:
+		u, err := d.uint(1 << (code - 0xcc))
+		if err != nil {
+			return nil, err
+		}
+		return json.Number(strconv.FormatUint(u, 10)), nil
+	case 0xd0, 0xd1, 0xd2, 0xd3:
+		size := 1 << (code - 0xd0)
+		u, err := d.uint(size)
+		if err != nil {
+			return nil, err
+		}
+		// Sign-extend from the encoded width
+		shift := 64 - 8*size
+		return json.Number(strconv.FormatInt(int64(u<<shift)>>shift, 10)), nil
+	case 0xdc:
+		n, err := d.length(2)
+		if err != nil {
+			return nil, err
+		}
+		return d.decodeArray(n)
+	case 0xdd:
+		n, err := d.length(4)
+		if err != nil {
+			return nil, err
+		}
+		return d.decodeArray(n)
+	case 0xde:
+		n, err := d.length(2)
+		if err != nil {
+			return nil, err
+		}
+		return d.decodeMap(n)
+	case 0xdf:
+		n, err := d.length(4)
+		if err != nil {
+			return nil, err
+		}
+		return d.decodeMap(n)
+	}
+	return nil, fmt.Errorf("msgpack: unsupported type code 0x%02x", code)
+}
+
+// decodeArray reads n array elements
+func (d *decoder) decodeArray(n int) (interface{}, error) {
+	// Every element takes at least one byte, which bounds the allocation
+	if n > len(d.data)-d.pos {
+		return nil, errShortData
+	}
+	if d.depth++; d.depth > MaxDepth {
+		return nil, errTooDeep
+	}
+	defer func() { d.depth-- }()
+	items := make([]interface{}, n)
+	for i := range items {
+		item, err := d.decode()
+		if err != nil {
+			return nil, err
+		}
+		items[i] = item
+	}
+	return items, nil
+}
+
+// decodeMap reads n key-value pairs with string keys
+func (d *decoder) decodeMap(n int) (interface{}, error) {
+	if n > len(d.data)-d.pos {
+		return nil, errShortData
+	}
+	if d.depth++; d.depth > MaxDepth {
+		return nil, errTooDeep
+	}
+	defer func() { d.depth-- }()
+	m := make(map[string]interface{}, n)
+	for i := 0; i < n; i++ {
+		key, err := d.decode()
+		if err != nil {
+			return nil, err
+		}
+		k, ok := key.(string)
+		if !ok {
+			return nil, fmt.Errorf("msgpack: map key of type %T, want string", key)
+		}
+		value, err := d.decode()
+		if err != nil {
+			return nil, err
+		}
+		m[k] = value
+	}
+	return m, nil
+}
+
+// decodeString reads n bytes as a string
+func (d *decoder) decodeString(n int) (interface{}, error) {
+	if n > len(d.data)-d.pos {
+		return nil, errShortData
+	}
+	s := string(d.data[d.pos : d.pos+n])
+	d.pos += n
+	return s, nil
+}
+
+// byte reads one byte
+func (d *decoder) byte() (byte, error) {
+	if d.pos >= len(d.data) {
+		return 0, errShortData
+	}
+	b := d.data[d.pos]
+	d.pos++
+	return b, nil
+}
+
+// uint reads a big-endian unsigned integer of size bytes
+func (d *decoder) uint(size int) (uint64, error) {
+	if size > len(d.data)-d.pos {
+		return 0, errShortData
+	}
+	var u uint64
+	for _, b := range d.data[d.pos : d.pos+size] {
+		u = u<<8 | uint64(b)
+	}
+	d.pos += size
+	return u, nil
+}
+
+// length reads a string, array or map length of size bytes. The length is
+// checked against the remaining input before it is converted to int, where a
+// 32-bit length could otherwise wrap to a negative value.
+func (d *decoder) length(size int) (int, error) {
+	n, err := d.uint(size)
+	if err != nil {
+		return 0, err
+	}
+	if n > uint64(len(d.data)-d.pos) {
+		return 0, errShortData
+	}
+	return int(n), nil
+}
+
+// floatNumber converts a decoded float for re-encoding as JSON, which has no
+// NaN or infinities
+func floatNumber(f float64) (interface{}, error) {
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		return nil, fmt.Errorf("msgpack: %v cannot be represented", f)
+	}
+	return json.Number(strconv.FormatFloat(f, 'g', -1, 64)), nil
+}
@@ -0,0 +1,133 @@
+package msgpack
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestCodec_Marshal(t *testing.T) {
+	tests := []struct {
+		name  string
+		value interface{}
+		want  string
+	}{
+		{name: "nil", value: nil, want: "c0"},
+		{name: "booleans", value: []bool{true, false}, want: "92c3c2"},
+		{name: "positive fixint", value: 1, want: "01"},
+		{name: "negative fixint", value: -1, want: "ff"},
+		{name: "uint16", value: 300, want: "cd012c"},
+		{name: "int8", value: -100, want: "d09c"},
+		{name: "int32", value: -70000, want: "d2fffeee90"},
+		{name: "float", value: 1.5, want: "cb3ff8000000000000"},
+		{name: "fixstr", value: "hi", want: "a26869"},
+		{name: "str8", value: strings.Repeat("a", 32), want: "d920" + strings.Repeat("61", 32)},
+		{name: "map with sorted keys", value: map[string]int{"b": 2, "a": 1}, want: "82a16101a16202"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := Codec{}.Marshal(tt.value)
+			if err != nil {
+				t.Fatalf("Marshal() error = %v", err)
+			}
+			if got := hex.EncodeToString(data); got != tt.want {
+				t.Errorf("Marshal() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCodec_Roundtrip(t *testing.T) {
+	type message struct {
+		ID      string          `json:"id"`
+		Seq     uint64          `json:"seq"`
+		Big     uint64          `json:"big"`
+		Score   float64         `json:"score"`
+		Tags    []string        `json:"tags"`
+		Payload json.RawMessage `json:"payload"`
+		Missing *string         `json:"missing"`
+	}
+	in := message{
+		ID:      "msg-1",
+		Seq:     70000,
+		Big:     1 << 63,
+		Score:   -0.25,
+		Tags:    []string{"x", strings.Repeat("y", 300)},
+		Payload: json.RawMessage(`{"content":"Hello","n":[1,-2,3.5]}`),
+	}
+
+	data, err := Codec{}.Marshal(in)
+	if err != nil {
+		t.Fatalf("Marshal() error = %v", err)
+	}
+	jsonData, _ := json.Marshal(in)
+	if len(data) >= len(jsonData) {
+		t.Errorf("msgpack size %d is not smaller than JSON size %d", len(data), len(jsonData))
+	}
+
+	var out message
+	if err := (Codec{}).Unmarshal(data, &out); err != nil {
+		t.Fatalf("Unmarshal() error = %v", err)
+	}
+	if out.ID != in.ID || out.Seq != in.Seq || out.Big != in.Big || out.Score != in.Score || out.Missing != nil {
+		t.Errorf("Unmarshal() = %+v, want %+v", out, in)
+	}
+	if len(out.Tags) != 2 || out.Tags[1] != in.Tags[1] {
+		t.Errorf("Tags = %v, want %v", out.Tags, in.Tags)
+	}
+	if !bytes.Equal(out.Payload, in.Payload) {
+		t.Errorf("Payload = %s, want %s", out.Payload, in.Payload)
+	}
+}
+
+func TestCodec_UnmarshalErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+	}{
+		{name: "empty", data: ""},
+		{name: "truncated string", data: "a568"},
+		{name: "truncated map", data: "82a161"},
+		{name: "non-string key", data: "810101"},
+		{name: "extension type", data: "d40100"},
+		{name: "trailing bytes", data: "0101"},
+		{name: "huge array length", data: "ddffffffff"},
+		{name: "huge map length", data: "dfffffffff"},
+		{name: "huge string length", data: "dbffffffff"},
+		{name: "huge binary length", data: "c6ffffffff"},
+		{name: "negative int32 string length", data: "db80000000"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, _ := hex.DecodeString(tt.data)
+			var v interface{}
+			if err := (Codec{}).Unmarshal(data, &v); err == nil {
+				t.Errorf("Unmarshal(%s) error = nil, want error", tt.data)
+			}
+		})
+	}
+}
+
+func TestCodec_UnmarshalDepth(t *testing.T) {
+	nested := func(depth int) []byte {
+		data := bytes.Repeat([]byte{0x91}, depth)
+		return append(data, 0xc0)
+	}
+
+	var v interface{}
+	if err := (Codec{}).Unmarshal(nested(MaxDepth), &v); err != nil {
+		t.Errorf("Unmarshal() at MaxDepth error = %v", err)
+	}
+
+	// A hostile body nested far deeper fails cleanly instead of exhausting
+	// the stack
+	for _, depth := range []int{MaxDepth + 1, 1 << 20} {
+		if err := (Codec{}).Unmarshal(nested(depth), &v); err == nil {
+			t.Errorf("Unmarshal() at depth %d error = nil, want error", depth)
+		}
+	}
+}
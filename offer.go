@@ -1,26 +1,66 @@
 package atoa
 
 import (
-	"bytes"
 	"context"
-	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
+	"net/url"
+	"time"
 )
 
+// OfferType identifies the kind of thing an offer provides
+type OfferType string
+
+const (
+	// OfferTypeService is an offer to perform a service on request
+	OfferTypeService OfferType = "service"
+	// OfferTypeData is an offer to provide a dataset
+	OfferTypeData OfferType = "data"
+	// OfferTypeCompute is an offer of compute capacity
+	OfferTypeCompute OfferType = "compute"
+)
+
+// IsValidOfferType reports whether t is one of the predefined offer types
+func IsValidOfferType(t string) bool {
+	switch OfferType(t) {
+	case OfferTypeService, OfferTypeData, OfferTypeCompute:
+		return true
+	default:
+		return false
+	}
+}
+
 // Offer represents a service offer from an agent
 type Offer struct {
 	Header       OfferHeader       `json:"header"`
 	Metadata     OfferMetadata     `json:"metadata"`
 	Requirements OfferRequirements `json:"requirements"`
+	// Verified is set by the platform when the publishing org is verified
+	Verified bool `json:"verified"`
+}
+
+// Validate checks if the Offer has all required fields
+func (o *Offer) Validate() error {
+	var errs ValidationErrors
+	if o.Header.ID == "" {
+		errs.add("header.id", "is required")
+	}
+	if o.Header.Title == "" {
+		errs.add("header.title", "is required")
+	}
+	if o.Header.Type == "" {
+		errs.add("header.type", "is required")
+	}
+	return errs.err()
 }
 
 // OfferHeader contains the basic information about an offer
 type OfferHeader struct {
-	ID          string `json:"id"`
-	Title       string `json:"title"`
-	Description string `json:"description"`
-	Type        string `json:"type"`
+	ID          string    `json:"id"`
+	Title       string    `json:"title"`
+	Description string    `json:"description"`
+	Type        OfferType `json:"type"`
 }
 
 // OfferMetadata contains additional information about the offer
@@ -48,74 +88,255 @@ type Session struct {
 	Status      string `json:"status"`
 }
 
-// ListOffers retrieves a list of available offers
+// Session status values
+const (
+	SessionStatusActive  = "active"
+	SessionStatusExpired = "expired"
+	SessionStatusClosed  = "closed"
+)
+
+// SessionListOptions filters the sessions returned by ListSessions. Empty
+// fields do not filter.
+type SessionListOptions struct {
+	Status  string
+	OfferID string
+}
+
+// Validate checks that the filter values are supported
+func (o SessionListOptions) Validate() error {
+	switch o.Status {
+	case "", SessionStatusActive, SessionStatusExpired, SessionStatusClosed:
+		return nil
+	default:
+		var errs ValidationErrors
+		errs.add("status", fmt.Sprintf("%q is not a known session status", o.Status))
+		return errs.err()
+	}
+}
+
+// ListOffers retrieves a list of available offers. It fails with
+// ErrAgentNotVerified, without a request, when Token is not verified unless
+// SkipVerifiedCheck is set. With VerifiedOffersOnly, offers not marked
+// Verified are dropped from the result.
 func (c *AgentClient) ListOffers(ctx context.Context) ([]Offer, error) {
-	req, err := http.NewRequestWithContext(ctx, "GET", c.BaseURL+"/offers", nil)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %w", err)
+	if err := c.checkVerified(gateVerified); err != nil {
+		return nil, err
+	}
+
+	var offers []Offer
+	if err := c.doJSON(ctx, http.MethodGet, "/offers", nil, &offers); err != nil {
+		return nil, err
+	}
+	if c.VerifiedOffersOnly {
+		offers = verifiedOffers(offers)
 	}
+	return offers, nil
+}
 
-	// Set authorization header
-	if c.Token != "" {
-		req.Header.Set("Authorization", "Bearer "+c.Token)
+// verifiedOffers returns the offers marked Verified, in order
+func verifiedOffers(offers []Offer) []Offer {
+	verified := make([]Offer, 0, len(offers))
+	for _, offer := range offers {
+		if offer.Verified {
+			verified = append(verified, offer)
+		}
 	}
+	return verified
+}
 
-	resp, err := c.HTTP.Do(req)
-	if err != nil {
-		return nil, fmt.Errorf("failed to send request: %w", err)
+// GetOffer retrieves a single offer by ID
+func (c *AgentClient) GetOffer(ctx context.Context, offerID string) (*Offer, error) {
+	if offerID == "" {
+		return nil, errors.New("offer id is required")
 	}
-	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	var offer Offer
+	if err := c.doJSON(ctx, http.MethodGet, "/offers/"+url.PathEscape(offerID), nil, &offer); err != nil {
+		if hasStatus(err, http.StatusNotFound) {
+			return nil, ErrOfferNotFound
+		}
+		return nil, err
 	}
+	return &offer, nil
+}
 
-	var offers []Offer
-	if err := json.NewDecoder(resp.Body).Decode(&offers); err != nil {
-		return nil, fmt.Errorf("failed to decode response: %w", err)
+// UpdateOffer replaces a published offer, identified by offer.Header.ID, and
+// returns the offer as stored by the platform. Metadata.UpdatedAt is set to
+// the current time.
+func (c *AgentClient) UpdateOffer(ctx context.Context, offer Offer) (*Offer, error) {
+	if offer.Header.ID == "" {
+		return nil, errors.New("offer id is required to update an offer")
+	}
+	if err := offer.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid offer: %w", err)
+	}
+	if !c.AllowCustomOfferTypes && !IsValidOfferType(string(offer.Header.Type)) {
+		return nil, fmt.Errorf("invalid offer: unknown offer type %q", offer.Header.Type)
+	}
+
+	offer.Metadata.UpdatedAt = timeNow().UTC().Format(time.RFC3339)
+
+	var updated Offer
+	if err := c.doJSON(ctx, http.MethodPut, "/offers/"+url.PathEscape(offer.Header.ID), offer, &updated); err != nil {
+		if hasStatus(err, http.StatusNotFound) {
+			return nil, ErrOfferNotFound
+		}
+		return nil, err
+	}
+	return &updated, nil
+}
+
+// DeleteOffer withdraws a published offer
+func (c *AgentClient) DeleteOffer(ctx context.Context, offerID string) error {
+	if offerID == "" {
+		return errors.New("offer id is required")
+	}
+
+	err := c.doJSON(ctx, http.MethodDelete, "/offers/"+url.PathEscape(offerID), nil, nil)
+	if hasStatus(err, http.StatusNotFound) {
+		return ErrOfferNotFound
+	}
+	return err
+}
+
+// BookmarkOffer adds an offer to this agent's bookmarks. It returns
+// ErrAlreadyBookmarked if the offer is bookmarked already.
+func (c *AgentClient) BookmarkOffer(ctx context.Context, offerID string) error {
+	if offerID == "" {
+		return errors.New("offer id is required")
 	}
 
+	err := c.doJSON(ctx, http.MethodPost, "/offers/"+url.PathEscape(offerID)+"/bookmark", nil, nil)
+	switch {
+	case hasStatus(err, http.StatusNotFound):
+		return ErrOfferNotFound
+	case hasStatus(err, http.StatusConflict):
+		return ErrAlreadyBookmarked
+	}
+	return err
+}
+
+// ListBookmarks retrieves the offers this agent has bookmarked
+func (c *AgentClient) ListBookmarks(ctx context.Context) ([]Offer, error) {
+	var offers []Offer
+	if err := c.doJSON(ctx, http.MethodGet, "/bookmarks", nil, &offers); err != nil {
+		return nil, err
+	}
 	return offers, nil
 }
 
-// CreateSession establishes a new session with an offer
+// CreateSession establishes a new session with an offer. When
+// CheckRequirements is set, the offer is fetched first and the agent's
+// capabilities are checked against its requirements. Like ListOffers, it
+// fails early with ErrAgentNotVerified for unverified tokens.
 func (c *AgentClient) CreateSession(ctx context.Context, offerID string) (*Session, error) {
-	payload := struct {
-		OfferID string `json:"offer_id"`
-	}{
-		OfferID: offerID,
+	if err := c.checkVerified(gateVerified); err != nil {
+		return nil, err
+	}
+
+	if c.CheckRequirements {
+		offer, err := c.GetOffer(ctx, offerID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch offer: %w", err)
+		}
+		if err := c.checkRequirements(offer); err != nil {
+			return nil, err
+		}
+	}
+
+	return c.createSession(ctx, offerID)
+}
+
+// CreateSessionForOffer establishes a new session with an offer the caller
+// already holds, checking its requirements locally when CheckRequirements is set
+func (c *AgentClient) CreateSessionForOffer(ctx context.Context, offer *Offer) (*Session, error) {
+	if err := c.checkVerified(gateVerified); err != nil {
+		return nil, err
 	}
 
-	body, err := json.Marshal(payload)
+	if c.CheckRequirements {
+		if err := c.checkRequirements(offer); err != nil {
+			return nil, err
+		}
+	}
+
+	return c.createSession(ctx, offer.Header.ID)
+}
+
+// NegotiateSession agrees on the capabilities shared by this agent and the
+// remote agent, then creates a session for the offer. It returns
+// ErrNoCommonCapabilities without creating a session if there are none.
+func (c *AgentClient) NegotiateSession(ctx context.Context, offerID string, remoteCaps []string) (*Session, []string, error) {
+	claims, err := PeekAgentClaims(c.Token())
 	if err != nil {
-		return nil, fmt.Errorf("failed to marshal request: %w", err)
+		return nil, nil, fmt.Errorf("failed to decode agent token: %w", err)
+	}
+
+	capabilities := NegotiateCapabilities(claims.Capabilities, remoteCaps)
+	if len(capabilities) == 0 {
+		return nil, nil, ErrNoCommonCapabilities
 	}
 
-	req, err := http.NewRequestWithContext(ctx, "POST", c.BaseURL+"/sessions", bytes.NewBuffer(body))
+	session, err := c.CreateSession(ctx, offerID)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %w", err)
+		return nil, nil, err
 	}
+	return session, capabilities, nil
+}
 
-	req.Header.Set("Content-Type", "application/json")
-	// Set authorization header
-	if c.Token != "" {
-		req.Header.Set("Authorization", "Bearer "+c.Token)
+// matchOptions returns the capability matching options set on the client
+func (c *AgentClient) matchOptions() []MatchOption {
+	if c.WildcardCapabilities {
+		return []MatchOption{WithWildcards()}
 	}
+	return nil
+}
 
-	resp, err := c.HTTP.Do(req)
+// checkRequirements checks the offer against the capabilities in the agent token
+func (c *AgentClient) checkRequirements(offer *Offer) error {
+	claims, err := PeekAgentClaims(c.Token())
 	if err != nil {
-		return nil, fmt.Errorf("failed to send request: %w", err)
+		return fmt.Errorf("failed to decode agent token: %w", err)
 	}
-	defer resp.Body.Close()
+	return offer.Requirements.Check(claims.Capabilities, c.matchOptions()...)
+}
 
-	if resp.StatusCode != http.StatusCreated {
-		return nil, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+// createSession sends the session creation request
+func (c *AgentClient) createSession(ctx context.Context, offerID string) (*Session, error) {
+	payload := struct {
+		OfferID string `json:"offer_id"`
+	}{
+		OfferID: offerID,
 	}
 
 	var session Session
-	if err := json.NewDecoder(resp.Body).Decode(&session); err != nil {
-		return nil, fmt.Errorf("failed to decode response: %w", err)
+	if err := c.doJSON(ctx, http.MethodPost, "/sessions", payload, &session); err != nil {
+		return nil, err
 	}
-
 	return &session, nil
 }
+
+// ListSessions retrieves the agent's sessions matching the given options
+func (c *AgentClient) ListSessions(ctx context.Context, opts SessionListOptions) ([]Session, error) {
+	if err := opts.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid session list options: %w", err)
+	}
+
+	query := url.Values{}
+	if opts.Status != "" {
+		query.Set("status", opts.Status)
+	}
+	if opts.OfferID != "" {
+		query.Set("offer_id", opts.OfferID)
+	}
+	path := "/sessions"
+	if len(query) > 0 {
+		path += "?" + query.Encode()
+	}
+
+	var sessions []Session
+	if err := c.doJSON(ctx, http.MethodGet, path, nil, &sessions); err != nil {
+		return nil, err
+	}
+	return sessions, nil
+}
@@ -0,0 +1,118 @@
+package atoa
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// offerCache holds the result of the last ListOffers call made through
+// ListOffersCached
+type offerCache struct {
+	mu        sync.Mutex
+	offers    []Offer
+	fetchedAt time.Time
+	valid     bool
+	inflight  *offerFetch
+	// generation is bumped by InvalidateOfferCache so that a fetch started
+	// before the invalidation does not repopulate the cache
+	generation int
+}
+
+// offerFetch is a ListOffers call shared by all callers that miss the cache
+// while it is running
+type offerFetch struct {
+	done       chan struct{}
+	generation int
+	offers     []Offer
+	err        error
+}
+
+// ListOffersCached returns the offers from the last fetch if it is younger than
+// ttl, and calls ListOffers otherwise. Concurrent callers that miss the cache
+// share a single in-flight request. The shared request is not canceled by any
+// one caller's ctx; each caller stops waiting when its own ctx is done.
+func (c *AgentClient) ListOffersCached(ctx context.Context, ttl time.Duration) ([]Offer, error) {
+	cache := &c.offerCache
+
+	cache.mu.Lock()
+	if cache.valid && time.Since(cache.fetchedAt) < ttl {
+		offers := copyOffers(cache.offers)
+		cache.mu.Unlock()
+		return offers, nil
+	}
+
+	fetch := cache.inflight
+	if fetch == nil {
+		fetch = &offerFetch{done: make(chan struct{}), generation: cache.generation}
+		cache.inflight = fetch
+		go c.fetchOffers(context.WithoutCancel(ctx), fetch)
+	}
+	cache.mu.Unlock()
+
+	select {
+	case <-fetch.done:
+		if fetch.err != nil {
+			return nil, fetch.err
+		}
+		return copyOffers(fetch.offers), nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// InvalidateOfferCache discards cached offers so the next ListOffersCached
+// call fetches them again
+func (c *AgentClient) InvalidateOfferCache() {
+	cache := &c.offerCache
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+	cache.valid = false
+	cache.offers = nil
+	cache.generation++
+}
+
+// fetchOffers runs a shared ListOffers call and stores a successful result
+func (c *AgentClient) fetchOffers(ctx context.Context, fetch *offerFetch) {
+	offers, err := c.ListOffers(ctx)
+
+	cache := &c.offerCache
+	cache.mu.Lock()
+	if err == nil && fetch.generation == cache.generation {
+		cache.offers = offers
+		cache.fetchedAt = time.Now()
+		cache.valid = true
+	}
+	cache.inflight = nil
+	cache.mu.Unlock()
+
+	fetch.offers, fetch.err = offers, err
+	close(fetch.done)
+}
+
+// copyOffers returns a deep copy of offers so callers cannot modify the cache
+func copyOffers(offers []Offer) []Offer {
+	if offers == nil {
+		return nil
+	}
+	copied := make([]Offer, len(offers))
+	for i, offer := range offers {
+		copied[i] = copyOffer(offer)
+	}
+	return copied
+}
+
+// copyOffer returns offer with its Tags and Capabilities slices copied
+func copyOffer(offer Offer) Offer {
+	offer.Metadata.Tags = cloneStrings(offer.Metadata.Tags)
+	offer.Requirements.Capabilities = cloneStrings(offer.Requirements.Capabilities)
+	return offer
+}
+
+// cloneStrings copies s, keeping nil as nil
+func cloneStrings(s []string) []string {
+	if s == nil {
+		return nil
+	}
+	return append([]string{}, s...)
+}
@@ -0,0 +1,146 @@
+package atoa
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestListOffersCached(t *testing.T) {
+	var requests int32
+	release := make(chan struct{})
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		<-release
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`[{"header": {"id": "offer-1", "title": "Test Offer", "type": "service"}}]`))
+	}))
+	defer ts.Close()
+
+	client := NewAgentClient(ts.URL)
+	client.SetToken("valid-token")
+
+	// Concurrent misses share one request
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			offers, err := client.ListOffersCached(context.Background(), time.Minute)
+			if err != nil {
+				t.Errorf("ListOffersCached() error = %v", err)
+				return
+			}
+			if len(offers) != 1 {
+				t.Errorf("len(offers) = %v, want %v", len(offers), 1)
+			}
+		}()
+	}
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	if got := atomic.LoadInt32(&requests); got != 1 {
+		t.Errorf("server received %d requests, want 1", got)
+	}
+
+	// Fresh entries are served from the cache
+	if _, err := client.ListOffersCached(context.Background(), time.Minute); err != nil {
+		t.Fatalf("ListOffersCached() error = %v", err)
+	}
+	if got := atomic.LoadInt32(&requests); got != 1 {
+		t.Errorf("server received %d requests, want 1", got)
+	}
+
+	// Invalidation forces a refetch
+	client.InvalidateOfferCache()
+	if _, err := client.ListOffersCached(context.Background(), time.Minute); err != nil {
+		t.Fatalf("ListOffersCached() error = %v", err)
+	}
+	if got := atomic.LoadInt32(&requests); got != 2 {
+		t.Errorf("server received %d requests, want 2", got)
+	}
+}
+
+func TestListOffersCached_WaiterCanceled(t *testing.T) {
+	release := make(chan struct{})
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`[{"header": {"id": "offer-1", "title": "Test Offer", "type": "service"}}]`))
+	}))
+	defer ts.Close()
+
+	client := NewAgentClient(ts.URL)
+	client.SetToken("valid-token")
+
+	// The first caller starts the shared fetch and gives up early
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	errA := make(chan error, 1)
+	go func() {
+		_, err := client.ListOffersCached(ctx, time.Minute)
+		errA <- err
+	}()
+	time.Sleep(5 * time.Millisecond)
+
+	type result struct {
+		offers []Offer
+		err    error
+	}
+	resB := make(chan result, 1)
+	go func() {
+		offers, err := client.ListOffersCached(context.Background(), time.Minute)
+		resB <- result{offers, err}
+	}()
+
+	if err := <-errA; !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("first caller error = %v, want %v", err, context.DeadlineExceeded)
+	}
+	close(release)
+
+	res := <-resB
+	if res.err != nil {
+		t.Fatalf("second caller error = %v", res.err)
+	}
+	if len(res.offers) != 1 {
+		t.Errorf("len(offers) = %v, want %v", len(res.offers), 1)
+	}
+}
+
+func TestListOffersCached_ReturnsCopies(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`[{"header": {"id": "offer-1", "title": "Test Offer", "type": "service"}, "metadata": {"tags": ["a"]}, "requirements": {"capabilities": ["text"]}}]`))
+	}))
+	defer ts.Close()
+
+	client := NewAgentClient(ts.URL)
+	client.SetToken("valid-token")
+
+	offers, err := client.ListOffersCached(context.Background(), time.Minute)
+	if err != nil {
+		t.Fatalf("ListOffersCached() error = %v", err)
+	}
+	offers[0].Metadata.Tags[0] = "changed"
+	offers[0].Requirements.Capabilities[0] = "changed"
+
+	offers, err = client.ListOffersCached(context.Background(), time.Minute)
+	if err != nil {
+		t.Fatalf("ListOffersCached() error = %v", err)
+	}
+	if got := offers[0].Metadata.Tags[0]; got != "a" {
+		t.Errorf("cached tag = %q, want %q", got, "a")
+	}
+	if got := offers[0].Requirements.Capabilities[0]; got != "text" {
+		t.Errorf("cached capability = %q, want %q", got, "text")
+	}
+}
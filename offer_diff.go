@@ -0,0 +1,89 @@
+package atoa
+
+import "sort"
+
+// OfferDiff lists the differences between two offer snapshots. Each slice is
+// sorted by Header.ID.
+type OfferDiff struct {
+	Added   []Offer
+	Removed []Offer
+	Changed []OfferChange
+}
+
+// OfferChange is an offer present in both snapshots whose version or
+// requirements differ
+type OfferChange struct {
+	ID  string
+	Old Offer
+	New Offer
+	// VersionChanged reports a different Metadata.Version
+	VersionChanged bool
+	// RequirementsChanged reports different required capabilities, compared
+	// as sets, or a different MinVersion
+	RequirementsChanged bool
+}
+
+// DiffOffers compares two snapshots, such as the results of successive
+// ListOffers calls, matching offers by Header.ID. Changes to fields other than
+// the version and requirements are not reported. If a snapshot repeats an ID,
+// its last offer with that ID is used.
+func DiffOffers(old, current []Offer) OfferDiff {
+	oldByID, newByID := offersByID(old), offersByID(current)
+	diff := OfferDiff{Added: []Offer{}, Removed: []Offer{}, Changed: []OfferChange{}}
+
+	for _, id := range sortedKeys(newByID) {
+		offer := newByID[id]
+		previous, ok := oldByID[id]
+		if !ok {
+			diff.Added = append(diff.Added, offer)
+			continue
+		}
+
+		change := OfferChange{
+			ID:                  id,
+			Old:                 previous,
+			New:                 offer,
+			VersionChanged:      previous.Metadata.Version != offer.Metadata.Version,
+			RequirementsChanged: !equalRequirements(previous.Requirements, offer.Requirements),
+		}
+		if change.VersionChanged || change.RequirementsChanged {
+			diff.Changed = append(diff.Changed, change)
+		}
+	}
+
+	for _, id := range sortedKeys(oldByID) {
+		if _, ok := newByID[id]; !ok {
+			diff.Removed = append(diff.Removed, oldByID[id])
+		}
+	}
+	return diff
+}
+
+// offersByID indexes offers by Header.ID
+func offersByID(offers []Offer) map[string]Offer {
+	byID := make(map[string]Offer, len(offers))
+	for _, offer := range offers {
+		byID[offer.Header.ID] = offer
+	}
+	return byID
+}
+
+// sortedKeys returns the IDs of an offer index in order
+func sortedKeys(byID map[string]Offer) []string {
+	ids := make([]string, 0, len(byID))
+	for id := range byID {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// equalRequirements compares requirements, ignoring capability order and
+// duplicates
+func equalRequirements(a, b OfferRequirements) bool {
+	if a.MinVersion != b.MinVersion {
+		return false
+	}
+	return len(missingCapabilities(a.Capabilities, b.Capabilities, nil)) == 0 &&
+		len(missingCapabilities(b.Capabilities, a.Capabilities, nil)) == 0
+}
@@ -0,0 +1,77 @@
+package atoa
+
+import (
+	"testing"
+)
+
+func TestDiffOffers(t *testing.T) {
+	offer := func(id, version string, capabilities ...string) Offer {
+		return Offer{
+			Header:       OfferHeader{ID: id, Title: id, Type: OfferTypeService},
+			Metadata:     OfferMetadata{Version: version},
+			Requirements: OfferRequirements{Capabilities: capabilities},
+		}
+	}
+
+	old := []Offer{
+		offer("offer-d", "1", "text"),
+		offer("offer-a", "1", "text", "file"),
+		offer("offer-b", "1", "text"),
+		offer("offer-c", "1", "text"),
+		offer("offer-e", "1"),
+	}
+	retitled := offer("offer-c", "1", "text")
+	retitled.Header.Title = "Renamed"
+	current := []Offer{
+		offer("offer-f", "1"),
+		offer("offer-a", "1", "file", "text"),
+		offer("offer-b", "2", "text", "image"),
+		retitled,
+		offer("offer-e", "2"),
+		offer("offer-0", "1"),
+	}
+
+	diff := DiffOffers(old, current)
+
+	if got := offerIDs(diff.Added); !equalIDs(got, []string{"offer-0", "offer-f"}) {
+		t.Errorf("Added = %v, want [offer-0 offer-f]", got)
+	}
+	if got := offerIDs(diff.Removed); !equalIDs(got, []string{"offer-d"}) {
+		t.Errorf("Removed = %v, want [offer-d]", got)
+	}
+
+	// Reordered capabilities and other fields do not count as changes
+	tests := []struct {
+		id               string
+		wantVersion      bool
+		wantRequirements bool
+	}{
+		{id: "offer-b", wantVersion: true, wantRequirements: true},
+		{id: "offer-e", wantVersion: true, wantRequirements: false},
+	}
+	if len(diff.Changed) != len(tests) {
+		t.Fatalf("Changed = %+v, want %d changes", diff.Changed, len(tests))
+	}
+	for i, tt := range tests {
+		change := diff.Changed[i]
+		if change.ID != tt.id {
+			t.Errorf("Changed[%d].ID = %q, want %q", i, change.ID, tt.id)
+		}
+		if change.VersionChanged != tt.wantVersion {
+			t.Errorf("%s VersionChanged = %v, want %v", tt.id, change.VersionChanged, tt.wantVersion)
+		}
+		if change.RequirementsChanged != tt.wantRequirements {
+			t.Errorf("%s RequirementsChanged = %v, want %v", tt.id, change.RequirementsChanged, tt.wantRequirements)
+		}
+		if change.Old.Header.ID != tt.id || change.New.Header.ID != tt.id {
+			t.Errorf("%s Old/New = %q/%q", tt.id, change.Old.Header.ID, change.New.Header.ID)
+		}
+	}
+}
+
+func TestDiffOffers_Empty(t *testing.T) {
+	diff := DiffOffers(nil, nil)
+	if diff.Added == nil || diff.Removed == nil || diff.Changed == nil {
+		t.Errorf("DiffOffers(nil, nil) = %+v, want empty non-nil slices", diff)
+	}
+}
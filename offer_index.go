@@ -0,0 +1,123 @@
+package atoa
+
+import (
+	"sort"
+	"sync"
+)
+
+// OfferIndex is an in-memory offer registry with inverted indexes on required
+// capabilities and tags, for embedders that look offers up without a platform
+// round trip. It is safe for concurrent use.
+type OfferIndex struct {
+	mu           sync.RWMutex
+	offers       map[string]Offer
+	byCapability map[string]map[string]struct{}
+	byTag        map[string]map[string]struct{}
+}
+
+// NewOfferIndex creates an empty OfferIndex
+func NewOfferIndex() *OfferIndex {
+	return &OfferIndex{
+		offers:       make(map[string]Offer),
+		byCapability: make(map[string]map[string]struct{}),
+		byTag:        make(map[string]map[string]struct{}),
+	}
+}
+
+// Add indexes a copy of offer, replacing any offer with the same Header.ID
+func (idx *OfferIndex) Add(offer Offer) {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	offer = copyOffer(offer)
+	id := offer.Header.ID
+	idx.remove(id)
+
+	idx.offers[id] = offer
+	for _, capability := range offer.Requirements.Capabilities {
+		addToIndex(idx.byCapability, capability, id)
+	}
+	for _, tag := range offer.Metadata.Tags {
+		addToIndex(idx.byTag, tag, id)
+	}
+}
+
+// Remove drops the offer with the given ID, if present
+func (idx *OfferIndex) Remove(id string) {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	idx.remove(id)
+}
+
+// Len returns the number of indexed offers
+func (idx *OfferIndex) Len() int {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+	return len(idx.offers)
+}
+
+// FindByCapability returns the offers that require the capability, sorted by ID
+func (idx *OfferIndex) FindByCapability(capability string) []Offer {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+	return idx.lookup(idx.byCapability[capability])
+}
+
+// FindByTag returns the offers carrying the tag, sorted by ID
+func (idx *OfferIndex) FindByTag(tag string) []Offer {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+	return idx.lookup(idx.byTag[tag])
+}
+
+// remove drops an offer and its index entries. The caller holds mu.
+func (idx *OfferIndex) remove(id string) {
+	offer, ok := idx.offers[id]
+	if !ok {
+		return
+	}
+	delete(idx.offers, id)
+	for _, capability := range offer.Requirements.Capabilities {
+		removeFromIndex(idx.byCapability, capability, id)
+	}
+	for _, tag := range offer.Metadata.Tags {
+		removeFromIndex(idx.byTag, tag, id)
+	}
+}
+
+// lookup returns copies of the offers with the given IDs, sorted by ID,
+// skipping IDs that are no longer indexed. The caller holds mu.
+func (idx *OfferIndex) lookup(ids map[string]struct{}) []Offer {
+	sorted := make([]string, 0, len(ids))
+	for id := range ids {
+		sorted = append(sorted, id)
+	}
+	sort.Strings(sorted)
+
+	offers := make([]Offer, 0, len(sorted))
+	for _, id := range sorted {
+		if offer, ok := idx.offers[id]; ok {
+			offers = append(offers, copyOffer(offer))
+		}
+	}
+	return offers
+}
+
+// addToIndex records that the offer id has the given key
+func addToIndex(index map[string]map[string]struct{}, key, id string) {
+	ids, ok := index[key]
+	if !ok {
+		ids = make(map[string]struct{})
+		index[key] = ids
+	}
+	ids[id] = struct{}{}
+}
+
+// removeFromIndex forgets that the offer id has the given key
+func removeFromIndex(index map[string]map[string]struct{}, key, id string) {
+	ids := index[key]
+	delete(ids, id)
+	if len(ids) == 0 {
+		delete(index, key)
+	}
+}
@@ -0,0 +1,127 @@
+package atoa
+
+import (
+	"testing"
+)
+
+// offerIDs returns the header IDs of offers in order
+func offerIDs(offers []Offer) []string {
+	ids := make([]string, len(offers))
+	for i, offer := range offers {
+		ids[i] = offer.Header.ID
+	}
+	return ids
+}
+
+func equalIDs(got, want []string) bool {
+	if len(got) != len(want) {
+		return false
+	}
+	for i := range got {
+		if got[i] != want[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestOfferIndex(t *testing.T) {
+	idx := NewOfferIndex()
+	idx.Add(Offer{
+		Header:       OfferHeader{ID: "offer-b", Title: "Translation", Type: OfferTypeService},
+		Metadata:     OfferMetadata{Tags: []string{"nlp", "text"}},
+		Requirements: OfferRequirements{Capabilities: []string{"text"}},
+	})
+	idx.Add(Offer{
+		Header:       OfferHeader{ID: "offer-a", Title: "OCR", Type: OfferTypeService},
+		Metadata:     OfferMetadata{Tags: []string{"vision"}},
+		Requirements: OfferRequirements{Capabilities: []string{"text", "file"}},
+	})
+
+	tests := []struct {
+		name string
+		got  []Offer
+		want []string
+	}{
+		{name: "shared capability sorted by id", got: idx.FindByCapability("text"), want: []string{"offer-a", "offer-b"}},
+		{name: "single capability", got: idx.FindByCapability("file"), want: []string{"offer-a"}},
+		{name: "unknown capability", got: idx.FindByCapability("image"), want: []string{}},
+		{name: "tag", got: idx.FindByTag("nlp"), want: []string{"offer-b"}},
+		{name: "unknown tag", got: idx.FindByTag("audio"), want: []string{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := offerIDs(tt.got); !equalIDs(got, tt.want) {
+				t.Errorf("ids = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestOfferIndex_ReplaceAndRemove(t *testing.T) {
+	idx := NewOfferIndex()
+	idx.Add(Offer{
+		Header:       OfferHeader{ID: "offer-1"},
+		Metadata:     OfferMetadata{Tags: []string{"old"}},
+		Requirements: OfferRequirements{Capabilities: []string{"text"}},
+	})
+
+	// Re-adding an offer drops its old index entries
+	idx.Add(Offer{
+		Header:       OfferHeader{ID: "offer-1"},
+		Metadata:     OfferMetadata{Tags: []string{"new"}},
+		Requirements: OfferRequirements{Capabilities: []string{"file"}},
+	})
+	if idx.Len() != 1 {
+		t.Errorf("Len() = %v, want %v", idx.Len(), 1)
+	}
+	if got := idx.FindByTag("old"); len(got) != 0 {
+		t.Errorf("FindByTag(old) = %v, want none", offerIDs(got))
+	}
+	if got := idx.FindByCapability("text"); len(got) != 0 {
+		t.Errorf("FindByCapability(text) = %v, want none", offerIDs(got))
+	}
+	if got := offerIDs(idx.FindByTag("new")); !equalIDs(got, []string{"offer-1"}) {
+		t.Errorf("FindByTag(new) = %v, want [offer-1]", got)
+	}
+
+	idx.Remove("offer-1")
+	idx.Remove("missing")
+	if idx.Len() != 0 {
+		t.Errorf("Len() = %v, want %v", idx.Len(), 0)
+	}
+	if got := idx.FindByCapability("file"); len(got) != 0 {
+		t.Errorf("FindByCapability(file) = %v, want none", offerIDs(got))
+	}
+}
+
+func TestOfferIndex_CopiesOffers(t *testing.T) {
+	idx := NewOfferIndex()
+	tags := []string{"x"}
+	idx.Add(Offer{
+		Header:       OfferHeader{ID: "offer-1"},
+		Metadata:     OfferMetadata{Tags: tags},
+		Requirements: OfferRequirements{Capabilities: []string{"text"}},
+	})
+
+	// Changing the added slice or a returned offer leaves the index intact
+	tags[0] = "changed"
+	found := idx.FindByTag("x")
+	if len(found) != 1 {
+		t.Fatalf("FindByTag(x) = %v, want [offer-1]", offerIDs(found))
+	}
+	found[0].Metadata.Tags[0] = "y"
+	found[0].Requirements.Capabilities[0] = "file"
+
+	idx.Remove("offer-1")
+	if idx.Len() != 0 {
+		t.Errorf("Len() = %v, want %v", idx.Len(), 0)
+	}
+	if got := idx.FindByTag("x"); len(got) != 0 {
+		t.Errorf("FindByTag(x) = %v, want none", offerIDs(got))
+	}
+	if got := idx.FindByCapability("text"); len(got) != 0 {
+		t.Errorf("FindByCapability(text) = %v, want none", offerIDs(got))
+	}
+}
@@ -0,0 +1,169 @@
+package atoa
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+const (
+	// minStreamBackoff is the first delay before reconnecting a dropped stream
+	minStreamBackoff = 500 * time.Millisecond
+	// maxStreamBackoff caps the delay between reconnection attempts
+	maxStreamBackoff = 30 * time.Second
+)
+
+// OfferEventType is the kind of change reported by StreamOffers
+type OfferEventType string
+
+const (
+	// OfferEventAdded reports a newly published offer
+	OfferEventAdded OfferEventType = "added"
+	// OfferEventUpdated reports a change to a published offer
+	OfferEventUpdated OfferEventType = "updated"
+	// OfferEventRemoved reports a withdrawn offer
+	OfferEventRemoved OfferEventType = "removed"
+)
+
+// OfferEvent is a change to the offer registry. For removed offers, Offer may
+// only carry the header.
+type OfferEvent struct {
+	Type  OfferEventType
+	Offer Offer
+}
+
+// StreamOffers subscribes to offer registry changes from /offers/stream, a
+// server-sent event stream whose event names are the OfferEventType and whose
+// data is the offer as JSON. Errors connecting the first time are returned
+// directly. After that, dropped connections are reopened with exponential
+// backoff, resuming from the last event ID seen; the backoff is only reset
+// once a reopened stream has delivered an event. The channel is closed when
+// ctx is canceled, the client is closed, or a reconnection is rejected with
+// 401 or 403. Like ListOffers, it fails early with ErrAgentNotVerified for
+// unverified tokens.
+func (c *AgentClient) StreamOffers(ctx context.Context) (<-chan OfferEvent, error) {
+	if err := c.checkVerified(gateVerified); err != nil {
+		return nil, err
+	}
+
+	ctx, done, err := c.beginRequest(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := c.openOfferStream(ctx, "")
+	if err != nil {
+		done()
+		return nil, err
+	}
+
+	events := make(chan OfferEvent)
+	go func() {
+		defer done()
+		defer close(events)
+		c.streamOffers(ctx, resp, events)
+	}()
+	return events, nil
+}
+
+// streamOffers forwards events from resp to events, reconnecting whenever the
+// stream ends, until ctx is done or the platform rejects the credentials
+func (c *AgentClient) streamOffers(ctx context.Context, resp *http.Response, events chan<- OfferEvent) {
+	lastEventID := ""
+	backoff := minStreamBackoff
+
+	for {
+		if resp != nil {
+			received := false
+			readSSE(resp.Body, func(e sseEvent) bool {
+				received = true
+				if e.ID != "" {
+					lastEventID = e.ID
+				}
+				event, ok := decodeOfferEvent(e)
+				if !ok {
+					return true
+				}
+				select {
+				case events <- event:
+					return true
+				case <-ctx.Done():
+					return false
+				}
+			})
+			resp.Body.Close()
+
+			// A server that accepts and immediately closes is backed off
+			// like one that refuses the connection
+			if received {
+				backoff = minStreamBackoff
+			} else {
+				backoff = min(backoff*2, maxStreamBackoff)
+			}
+		}
+
+		timer := time.NewTimer(backoff)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+
+		var err error
+		resp, err = c.openOfferStream(ctx, lastEventID)
+		if hasStatus(err, http.StatusUnauthorized) || hasStatus(err, http.StatusForbidden) {
+			return
+		}
+		if err != nil {
+			resp = nil
+			backoff = min(backoff*2, maxStreamBackoff)
+		}
+	}
+}
+
+// openOfferStream connects to /offers/stream, resuming after lastEventID if
+// it is set
+func (c *AgentClient) openOfferStream(ctx context.Context, lastEventID string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.endpoint("/offers/stream"), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	c.setHeaders(req)
+	if token := c.Token(); token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	req.Header.Set("Accept", "text/event-stream")
+	if lastEventID != "" {
+		req.Header.Set("Last-Event-ID", lastEventID)
+	}
+
+	resp, err := c.HTTP.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open offer stream: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+		return nil, newAPIError(resp)
+	}
+	return resp, nil
+}
+
+// decodeOfferEvent converts a stream event into an OfferEvent, reporting false
+// for unknown event names and undecodable offers
+func decodeOfferEvent(e sseEvent) (OfferEvent, bool) {
+	eventType := OfferEventType(e.Event)
+	switch eventType {
+	case OfferEventAdded, OfferEventUpdated, OfferEventRemoved:
+	default:
+		return OfferEvent{}, false
+	}
+
+	var offer Offer
+	if err := json.Unmarshal(e.Data, &offer); err != nil {
+		return OfferEvent{}, false
+	}
+	return OfferEvent{Type: eventType, Offer: offer}, true
+}
@@ -0,0 +1,187 @@
+package atoa
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestStreamOffers_Reconnects(t *testing.T) {
+	var mu sync.Mutex
+	var lastEventIDs []string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/offers/stream" || r.Header.Get("Authorization") != "Bearer valid-token" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		mu.Lock()
+		lastEventIDs = append(lastEventIDs, r.Header.Get("Last-Event-ID"))
+		connection := len(lastEventIDs)
+		mu.Unlock()
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		if connection == 1 {
+			// Send two events, then drop the connection
+			fmt.Fprint(w, ": welcome\n\n")
+			fmt.Fprint(w, "id: 1\nevent: added\ndata: {\"header\": {\"id\": \"offer-1\", \"title\": \"One\", \"type\": \"service\"}}\n\n")
+			fmt.Fprint(w, "id: 2\nevent: unknown\ndata: {}\n\n")
+			return
+		}
+		fmt.Fprint(w, "id: 3\nevent: removed\ndata: {\"header\": {\"id\": \"offer-1\"}}\n\n")
+		w.(http.Flusher).Flush()
+		<-r.Context().Done()
+	}))
+	defer ts.Close()
+
+	client := &AgentClient{
+		BaseURL: ts.URL,
+		HTTP:    &http.Client{},
+	}
+	client.SetToken("valid-token")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	events, err := client.StreamOffers(ctx)
+	if err != nil {
+		t.Fatalf("StreamOffers() error = %v", err)
+	}
+
+	want := []OfferEvent{
+		{Type: OfferEventAdded, Offer: Offer{Header: OfferHeader{ID: "offer-1", Title: "One", Type: OfferTypeService}}},
+		{Type: OfferEventRemoved, Offer: Offer{Header: OfferHeader{ID: "offer-1"}}},
+	}
+	for i, w := range want {
+		select {
+		case got := <-events:
+			if got.Type != w.Type || got.Offer.Header != w.Offer.Header {
+				t.Errorf("event %d = %+v, want %+v", i, got, w)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("timed out waiting for event %d", i)
+		}
+	}
+
+	mu.Lock()
+	if len(lastEventIDs) != 2 || lastEventIDs[0] != "" || lastEventIDs[1] != "2" {
+		t.Errorf("Last-Event-ID headers = %q, want [\"\" \"2\"]", lastEventIDs)
+	}
+	mu.Unlock()
+
+	// Canceling the context closes the channel
+	cancel()
+	select {
+	case _, ok := <-events:
+		if ok {
+			t.Error("received an event after cancel, want closed channel")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("channel was not closed after cancel")
+	}
+}
+
+func TestStreamOffers_Errors(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+	defer ts.Close()
+
+	client := &AgentClient{
+		BaseURL: ts.URL,
+		HTTP:    &http.Client{},
+	}
+	client.SetToken("valid-token")
+
+	if _, err := client.StreamOffers(context.Background()); !hasStatus(err, http.StatusUnauthorized) {
+		t.Errorf("StreamOffers() error = %v, want 401 APIError", err)
+	}
+
+	client.Close()
+	if _, err := client.StreamOffers(context.Background()); err != ErrClientClosed {
+		t.Errorf("StreamOffers() after Close error = %v, want %v", err, ErrClientClosed)
+	}
+}
+
+func TestStreamOffers_StopsOnAuthFailure(t *testing.T) {
+	var mu sync.Mutex
+	connections := 0
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		connections++
+		connection := connections
+		mu.Unlock()
+
+		if connection > 1 {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		w.Header().Set("Content-Type", "text/event-stream")
+		fmt.Fprint(w, "id: 1\nevent: added\ndata: {\"header\": {\"id\": \"offer-1\"}}\n\n")
+	}))
+	defer ts.Close()
+
+	client := &AgentClient{BaseURL: ts.URL, HTTP: &http.Client{}}
+	client.SetToken("valid-token")
+
+	events, err := client.StreamOffers(context.Background())
+	if err != nil {
+		t.Fatalf("StreamOffers() error = %v", err)
+	}
+
+	// The first event arrives, then the rejected reconnection closes the
+	// channel instead of retrying forever
+	deadline := time.After(5 * time.Second)
+	received := 0
+	for open := true; open; {
+		select {
+		case _, open = <-events:
+			if open {
+				received++
+			}
+		case <-deadline:
+			t.Fatal("channel was not closed after 403 on reconnect")
+		}
+	}
+	if received != 1 {
+		t.Errorf("received %d events, want 1", received)
+	}
+	mu.Lock()
+	if connections != 2 {
+		t.Errorf("server received %d connections, want 2", connections)
+	}
+	mu.Unlock()
+}
+
+func TestStreamOffers_BacksOffEmptyStreams(t *testing.T) {
+	var mu sync.Mutex
+	connections := 0
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		connections++
+		mu.Unlock()
+		// Accept and close at once without sending anything
+		w.Header().Set("Content-Type", "text/event-stream")
+	}))
+	defer ts.Close()
+
+	client := &AgentClient{BaseURL: ts.URL, HTTP: &http.Client{}}
+	client.SetToken("valid-token")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if _, err := client.StreamOffers(ctx); err != nil {
+		t.Fatalf("StreamOffers() error = %v", err)
+	}
+
+	// Without backoff growth a reconnect would happen every 500ms; with it
+	// the second one waits 1s and the third 2s more
+	time.Sleep(1800 * time.Millisecond)
+	mu.Lock()
+	defer mu.Unlock()
+	if connections > 2 {
+		t.Errorf("server received %d connections in 1.8s, want at most 2", connections)
+	}
+}
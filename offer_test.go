@@ -6,8 +6,10 @@ import (
 	"crypto/elliptic"
 	"crypto/rand"
 	"encoding/json"
+	"errors"
 	"net/http"
 	"net/http/httptest"
+	"reflect"
 	"testing"
 )
 
@@ -103,13 +105,13 @@ func TestListOffers(t *testing.T) {
 			client := &AgentClient{
 				BaseURL: ts.URL,
 				HTTP:    &http.Client{},
-				Token:   "test-token",
 			}
+			client.SetToken("test-token")
 
 			if tt.tokenValid {
-				client.Token = "valid-token"
+				client.SetToken("valid-token")
 			} else {
-				client.Token = "invalid-token"
+				client.SetToken("invalid-token")
 			}
 
 			// Test ListOffers
@@ -232,13 +234,13 @@ func TestCreateSession(t *testing.T) {
 			client := &AgentClient{
 				BaseURL: ts.URL,
 				HTTP:    &http.Client{},
-				Token:   "test-token",
 			}
+			client.SetToken("test-token")
 
 			if tt.tokenValid {
-				client.Token = "valid-token"
+				client.SetToken("valid-token")
 			} else {
-				client.Token = "invalid-token"
+				client.SetToken("invalid-token")
 			}
 
 			// Test CreateSession
@@ -270,3 +272,404 @@ func TestCreateSession(t *testing.T) {
 		})
 	}
 }
+
+func TestListOffers_ResponseDecoder(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{"data": [{"header": {"id": "offer-1", "title": "Test Offer", "type": "service"}}]}`))
+	}))
+	defer ts.Close()
+
+	client := &AgentClient{
+		BaseURL: ts.URL,
+		HTTP:    &http.Client{},
+		// Unwrap the deployment's {"data": ...} envelope
+		ResponseDecoder: func(resp *http.Response, v interface{}) error {
+			var envelope struct {
+				Data json.RawMessage `json:"data"`
+			}
+			if err := json.NewDecoder(resp.Body).Decode(&envelope); err != nil {
+				return err
+			}
+			return json.Unmarshal(envelope.Data, v)
+		},
+	}
+	client.SetToken("valid-token")
+
+	offers, err := client.ListOffers(context.Background())
+	if err != nil {
+		t.Fatalf("ListOffers() error = %v", err)
+	}
+	if len(offers) != 1 || offers[0].Header.ID != "offer-1" {
+		t.Errorf("ListOffers() = %+v, want offer-1", offers)
+	}
+}
+
+func TestListOffers_VerifiedOffersOnly(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`[
+			{"header": {"id": "offer-1", "title": "Verified", "type": "service"}, "verified": true},
+			{"header": {"id": "offer-2", "title": "Unverified", "type": "service"}},
+			{"header": {"id": "offer-3", "title": "Also verified", "type": "data"}, "verified": true}
+		]`))
+	}))
+	defer ts.Close()
+
+	tests := []struct {
+		name    string
+		opts    []ClientOption
+		wantIDs []string
+	}{
+		{name: "all offers by default", opts: nil, wantIDs: []string{"offer-1", "offer-2", "offer-3"}},
+		{name: "verified only", opts: []ClientOption{WithVerifiedOffersOnly()}, wantIDs: []string{"offer-1", "offer-3"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := NewAgentClient(ts.URL, tt.opts...)
+			client.SetToken("valid-token")
+
+			offers, err := client.ListOffers(context.Background())
+			if err != nil {
+				t.Fatalf("ListOffers() error = %v", err)
+			}
+			if got := offerIDs(offers); !equalIDs(got, tt.wantIDs) {
+				t.Errorf("ListOffers() ids = %v, want %v", got, tt.wantIDs)
+			}
+		})
+	}
+}
+
+func TestListSessions(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			t.Errorf("expected GET request, got %s", r.Method)
+		}
+		if r.URL.Path != "/sessions" {
+			t.Errorf("expected path /sessions, got %s", r.URL.Path)
+		}
+		if got := r.URL.Query().Get("status"); got != "active" {
+			t.Errorf("status query = %q, want %q", got, "active")
+		}
+		if got := r.URL.Query().Get("offer_id"); got != "offer-1" {
+			t.Errorf("offer_id query = %q, want %q", got, "offer-1")
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`[{"session_id": "session-1", "offer_id": "offer-1", "status": "active"}]`))
+	}))
+	defer ts.Close()
+
+	client := &AgentClient{
+		BaseURL: ts.URL,
+		HTTP:    &http.Client{},
+	}
+	client.SetToken("valid-token")
+
+	sessions, err := client.ListSessions(context.Background(), SessionListOptions{
+		Status:  SessionStatusActive,
+		OfferID: "offer-1",
+	})
+	if err != nil {
+		t.Fatalf("ListSessions() error = %v", err)
+	}
+	if len(sessions) != 1 || sessions[0].SessionID != "session-1" {
+		t.Errorf("ListSessions() = %+v, want session-1", sessions)
+	}
+
+	var errs ValidationErrors
+	if _, err := client.ListSessions(context.Background(), SessionListOptions{Status: "paused"}); !errors.As(err, &errs) || errs[0].Field != "status" {
+		t.Errorf("ListSessions() with unknown status error = %v, want status ValidationErrors", err)
+	}
+}
+
+func TestGetOffer(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			t.Errorf("expected GET request, got %s", r.Method)
+		}
+		if r.Header.Get("Authorization") != "Bearer valid-token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		if r.URL.Path != "/offers/offer-1" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{"header": {"id": "offer-1", "title": "Test Offer", "type": "service"}}`))
+	}))
+	defer ts.Close()
+
+	client := &AgentClient{
+		BaseURL: ts.URL,
+		HTTP:    &http.Client{},
+	}
+	client.SetToken("valid-token")
+
+	offer, err := client.GetOffer(context.Background(), "offer-1")
+	if err != nil {
+		t.Fatalf("GetOffer() error = %v", err)
+	}
+	if offer.Header.Title != "Test Offer" {
+		t.Errorf("Title = %v, want %v", offer.Header.Title, "Test Offer")
+	}
+
+	if _, err := client.GetOffer(context.Background(), "missing"); !errors.Is(err, ErrOfferNotFound) {
+		t.Errorf("GetOffer() error = %v, want %v", err, ErrOfferNotFound)
+	}
+}
+
+func TestDeleteOffer(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodDelete {
+			t.Errorf("expected DELETE request, got %s", r.Method)
+		}
+		switch r.URL.Path {
+		case "/offers/offer-1":
+			w.WriteHeader(http.StatusNoContent)
+		case "/offers/foreign-offer":
+			http.Error(w, "offer belongs to another agent", http.StatusForbidden)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer ts.Close()
+
+	client := &AgentClient{
+		BaseURL: ts.URL,
+		HTTP:    &http.Client{},
+	}
+	client.SetToken("valid-token")
+
+	if err := client.DeleteOffer(context.Background(), "offer-1"); err != nil {
+		t.Errorf("DeleteOffer() error = %v", err)
+	}
+	if err := client.DeleteOffer(context.Background(), "missing"); !errors.Is(err, ErrOfferNotFound) {
+		t.Errorf("DeleteOffer() error = %v, want %v", err, ErrOfferNotFound)
+	}
+
+	var apiErr *APIError
+	err := client.DeleteOffer(context.Background(), "foreign-offer")
+	if !errors.As(err, &apiErr) || apiErr.Message != "offer belongs to another agent" {
+		t.Errorf("DeleteOffer() error = %v, want APIError with server message", err)
+	}
+}
+
+func TestBookmarkOffer(t *testing.T) {
+	bookmarked := map[string]bool{}
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer valid-token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == "/bookmarks":
+			w.Header().Set("Content-Type", "application/json")
+			w.Write([]byte(`[{"header": {"id": "offer-1", "title": "Test Offer", "type": "service"}}]`))
+		case r.Method == http.MethodPost && r.URL.Path == "/offers/offer-1/bookmark":
+			if bookmarked["offer-1"] {
+				http.Error(w, "already bookmarked", http.StatusConflict)
+				return
+			}
+			bookmarked["offer-1"] = true
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer ts.Close()
+
+	client := &AgentClient{
+		BaseURL: ts.URL,
+		HTTP:    &http.Client{},
+	}
+	client.SetToken("valid-token")
+
+	tests := []struct {
+		name    string
+		offerID string
+		wantErr error
+	}{
+		{name: "new bookmark", offerID: "offer-1", wantErr: nil},
+		{name: "already bookmarked", offerID: "offer-1", wantErr: ErrAlreadyBookmarked},
+		{name: "unknown offer", offerID: "missing", wantErr: ErrOfferNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := client.BookmarkOffer(context.Background(), tt.offerID); !errors.Is(err, tt.wantErr) {
+				t.Errorf("BookmarkOffer() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+
+	offers, err := client.ListBookmarks(context.Background())
+	if err != nil {
+		t.Fatalf("ListBookmarks() error = %v", err)
+	}
+	if len(offers) != 1 || offers[0].Header.ID != "offer-1" {
+		t.Errorf("ListBookmarks() = %+v, want [offer-1]", offers)
+	}
+}
+
+func TestUpdateOffer(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPut {
+			t.Errorf("expected PUT request, got %s", r.Method)
+		}
+		if r.URL.Path != "/offers/offer-1" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		var offer Offer
+		if err := json.NewDecoder(r.Body).Decode(&offer); err != nil {
+			t.Errorf("failed to decode request: %v", err)
+		}
+		if offer.Metadata.UpdatedAt == "2024-03-20T12:00:00Z" {
+			t.Error("UpdatedAt was not bumped")
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(offer)
+	}))
+	defer ts.Close()
+
+	client := &AgentClient{
+		BaseURL: ts.URL,
+		HTTP:    &http.Client{},
+	}
+	client.SetToken("valid-token")
+	offer := Offer{
+		Header:   OfferHeader{ID: "offer-1", Title: "Updated Offer", Type: "service"},
+		Metadata: OfferMetadata{UpdatedAt: "2024-03-20T12:00:00Z"},
+	}
+
+	updated, err := client.UpdateOffer(context.Background(), offer)
+	if err != nil {
+		t.Fatalf("UpdateOffer() error = %v", err)
+	}
+	if updated.Header.Title != "Updated Offer" {
+		t.Errorf("Title = %v, want %v", updated.Header.Title, "Updated Offer")
+	}
+
+	offer.Header.ID = "missing"
+	if _, err := client.UpdateOffer(context.Background(), offer); !errors.Is(err, ErrOfferNotFound) {
+		t.Errorf("UpdateOffer() error = %v, want %v", err, ErrOfferNotFound)
+	}
+
+	offer.Header.ID = ""
+	if _, err := client.UpdateOffer(context.Background(), offer); err == nil {
+		t.Error("UpdateOffer() without id error = nil, want error")
+	}
+
+	// Unknown offer types are rejected unless custom types are allowed
+	offer.Header.ID = "offer-1"
+	offer.Header.Type = "servcie"
+	if _, err := client.UpdateOffer(context.Background(), offer); err == nil {
+		t.Error("UpdateOffer() with unknown type error = nil, want error")
+	}
+	client.AllowCustomOfferTypes = true
+	if _, err := client.UpdateOffer(context.Background(), offer); err != nil {
+		t.Errorf("UpdateOffer() with custom type allowed error = %v", err)
+	}
+}
+
+func TestIsValidOfferType(t *testing.T) {
+	tests := []struct {
+		offerType string
+		want      bool
+	}{
+		{offerType: "service", want: true},
+		{offerType: "data", want: true},
+		{offerType: "compute", want: true},
+		{offerType: "Service", want: false},
+		{offerType: "servcie", want: false},
+		{offerType: "", want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.offerType, func(t *testing.T) {
+			if got := IsValidOfferType(tt.offerType); got != tt.want {
+				t.Errorf("IsValidOfferType(%q) = %v, want %v", tt.offerType, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestOfferValidate(t *testing.T) {
+	tests := []struct {
+		name   string
+		offer  Offer
+		fields []string
+	}{
+		{"valid", Offer{Header: OfferHeader{ID: "offer-1", Title: "Test Offer", Type: OfferTypeService}}, nil},
+		{"missing title", Offer{Header: OfferHeader{ID: "offer-1", Type: OfferTypeService}}, []string{"header.title"}},
+		{"empty", Offer{}, []string{"header.id", "header.title", "header.type"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.offer.Validate()
+			if tt.fields == nil {
+				if err != nil {
+					t.Errorf("Validate() error = %v, want nil", err)
+				}
+				return
+			}
+			var errs ValidationErrors
+			if !errors.As(err, &errs) {
+				t.Fatalf("Validate() error = %v, want ValidationErrors", err)
+			}
+			var fields []string
+			for _, e := range errs {
+				fields = append(fields, e.Field)
+			}
+			if !reflect.DeepEqual(fields, tt.fields) {
+				t.Errorf("Validate() fields = %v, want %v", fields, tt.fields)
+			}
+		})
+	}
+}
+
+func TestVerifiedGate(t *testing.T) {
+	requests := 0
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.WriteHeader(http.StatusForbidden)
+	}))
+	defer ts.Close()
+
+	client := &AgentClient{
+		BaseURL: ts.URL,
+		HTTP:    &http.Client{},
+	}
+	client.SetToken(issueTestAgentToken(t, false))
+
+	if _, err := client.ListOffers(context.Background()); !errors.Is(err, ErrAgentNotVerified) {
+		t.Errorf("ListOffers() error = %v, want %v", err, ErrAgentNotVerified)
+	}
+	if _, err := client.CreateSession(context.Background(), "offer-1"); !errors.Is(err, ErrAgentNotVerified) {
+		t.Errorf("CreateSession() error = %v, want %v", err, ErrAgentNotVerified)
+	}
+	if requests != 0 {
+		t.Errorf("server received %d requests, want 0", requests)
+	}
+
+	// With the check skipped the server gets to reject the request
+	client.SkipVerifiedCheck = true
+	_, err := client.ListOffers(context.Background())
+	if !hasStatus(err, http.StatusForbidden) {
+		t.Errorf("ListOffers() error = %v, want APIError with status 403", err)
+	}
+	if requests != 1 {
+		t.Errorf("server received %d requests, want 1", requests)
+	}
+}
@@ -0,0 +1,201 @@
+package atoa
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"net"
+	"net/http"
+	"time"
+)
+
+const (
+	// tunedMaxIdleConns is the total idle connection pool size of the tuned transport
+	tunedMaxIdleConns = 100
+	// tunedMaxIdleConnsPerHost keeps enough idle connections to the platform
+	// for high-throughput agents; the net/http default is 2
+	tunedMaxIdleConnsPerHost = 32
+	// tunedIdleConnTimeout is how long idle connections stay in the pool
+	tunedIdleConnTimeout = 90 * time.Second
+)
+
+// ClientOption configures an OrgClient or AgentClient
+type ClientOption func(*clientOptions)
+
+// clientOptions holds the settings collected from ClientOptions
+type clientOptions struct {
+	transport            *http.Transport
+	compressionThreshold int
+	hmacKeyID            string
+	hmacSecret           []byte
+	userAgent            string
+	header               http.Header
+	codec                Codec
+	verifiedOffersOnly   bool
+	clientCerts          []tls.Certificate
+	rootCAs              *x509.CertPool
+	retry                *RetryPolicy
+}
+
+// WithTransport makes the client send all requests through the given transport
+func WithTransport(transport *http.Transport) ClientOption {
+	return func(o *clientOptions) {
+		o.transport = transport
+	}
+}
+
+// WithTunedTransport makes the client use a transport with connection pooling
+// tuned for many requests to the same platform host
+func WithTunedTransport() ClientOption {
+	return func(o *clientOptions) {
+		o.transport = newTunedTransport()
+	}
+}
+
+// WithRequestCompression makes an AgentClient gzip-compress offer and message
+// request bodies larger than threshold bytes. Servers that answer 415
+// Unsupported Media Type get the request again uncompressed. OrgClient ignores
+// this option.
+func WithRequestCompression(threshold int) ClientOption {
+	return func(o *clientOptions) {
+		o.compressionThreshold = threshold
+	}
+}
+
+// WithVerifiedOffersOnly makes an AgentClient's ListOffers, and the calls
+// built on it, ignore offers from unverified orgs. OrgClient ignores this
+// option.
+func WithVerifiedOffersOnly() ClientOption {
+	return func(o *clientOptions) {
+		o.verifiedOffersOnly = true
+	}
+}
+
+// WithUserAgent sets the User-Agent header of every request, replacing
+// DefaultUserAgent
+func WithUserAgent(userAgent string) ClientOption {
+	return func(o *clientOptions) {
+		o.userAgent = userAgent
+	}
+}
+
+// WithHeader adds a header, such as a tenant ID, to every request. Headers the
+// client sets itself, like Authorization and Content-Type, take precedence.
+func WithHeader(key, value string) ClientOption {
+	return func(o *clientOptions) {
+		if o.header == nil {
+			o.header = make(http.Header)
+		}
+		o.header.Add(key, value)
+	}
+}
+
+// WithClientCert makes the client present cert during TLS handshakes, for
+// platforms that require mutual TLS. It composes with WithTransport and
+// WithTunedTransport in any order: the chosen transport is copied and the
+// certificate added to the copy's TLS config.
+func WithClientCert(cert tls.Certificate) ClientOption {
+	return func(o *clientOptions) {
+		o.clientCerts = append(o.clientCerts, cert)
+	}
+}
+
+// WithRootCAs makes the client verify the platform's certificate against
+// pool instead of the system roots. It composes with the transport options
+// like WithClientCert.
+func WithRootCAs(pool *x509.CertPool) ClientOption {
+	return func(o *clientOptions) {
+		o.rootCAs = pool
+	}
+}
+
+// WithRetry makes the client retry idempotent requests that fail
+// transiently, as described by RetryPolicy
+func WithRetry(policy RetryPolicy) ClientOption {
+	return func(o *clientOptions) {
+		o.retry = &policy
+	}
+}
+
+// newTunedTransport returns a transport with pooling and keep-alive settings
+// suitable for high-throughput agents
+func newTunedTransport() *http.Transport {
+	return &http.Transport{
+		Proxy: http.ProxyFromEnvironment,
+		DialContext: (&net.Dialer{
+			Timeout:   30 * time.Second,
+			KeepAlive: 30 * time.Second,
+		}).DialContext,
+		ForceAttemptHTTP2:     true,
+		MaxIdleConns:          tunedMaxIdleConns,
+		MaxIdleConnsPerHost:   tunedMaxIdleConnsPerHost,
+		IdleConnTimeout:       tunedIdleConnTimeout,
+		TLSHandshakeTimeout:   10 * time.Second,
+		ExpectContinueTimeout: 1 * time.Second,
+	}
+}
+
+// applyOptions collects the given options into a clientOptions
+func applyOptions(opts []ClientOption) *clientOptions {
+	o := &clientOptions{}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+// setRequestHeaders applies a client's User-Agent, extra headers and the
+// request ID to a new request, before the client sets its own headers
+func setRequestHeaders(req *http.Request, userAgent string, header http.Header) {
+	for key, values := range header {
+		for _, value := range values {
+			req.Header.Add(key, value)
+		}
+	}
+	if userAgent == "" {
+		userAgent = DefaultUserAgent
+	}
+	req.Header.Set("User-Agent", userAgent)
+	setRequestID(req)
+}
+
+// tlsTransport returns a copy of the configured transport, or of the net/http
+// default, with the client certificates and root CAs applied. The copy leaves
+// a transport passed to WithTransport untouched.
+func (o *clientOptions) tlsTransport() *http.Transport {
+	var transport *http.Transport
+	if o.transport != nil {
+		transport = o.transport.Clone()
+	} else {
+		transport = http.DefaultTransport.(*http.Transport).Clone()
+	}
+
+	if transport.TLSClientConfig == nil {
+		transport.TLSClientConfig = &tls.Config{MinVersion: tls.VersionTLS12}
+	}
+	certs := append([]tls.Certificate{}, transport.TLSClientConfig.Certificates...)
+	transport.TLSClientConfig.Certificates = append(certs, o.clientCerts...)
+	if o.rootCAs != nil {
+		transport.TLSClientConfig.RootCAs = o.rootCAs
+	}
+	return transport
+}
+
+// httpClient builds the HTTP client shared by all requests of one client
+// instance, so that connections are reused
+func (o *clientOptions) httpClient() *http.Client {
+	client := &http.Client{}
+	if o.transport != nil {
+		client.Transport = o.transport
+	}
+	if o.clientCerts != nil || o.rootCAs != nil {
+		client.Transport = o.tlsTransport()
+	}
+	if o.hmacSecret != nil {
+		base := client.Transport
+		if base == nil {
+			base = http.DefaultTransport
+		}
+		client.Transport = &hmacTransport{base: base, keyID: o.hmacKeyID, secret: o.hmacSecret}
+	}
+	return client
+}
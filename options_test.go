@@ -0,0 +1,229 @@
+package atoa
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"io"
+	"log"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestWithTransport(t *testing.T) {
+	transport := &http.Transport{}
+
+	orgClient := NewOrgClient("http://localhost", WithTransport(transport))
+	if orgClient.HTTP.Transport != transport {
+		t.Error("OrgClient does not use the given transport")
+	}
+
+	agentClient := NewAgentClient("http://localhost", WithTransport(transport))
+	if agentClient.HTTP.Transport != transport {
+		t.Error("AgentClient does not use the given transport")
+	}
+
+	// Without options the net/http default transport is used
+	if NewAgentClient("http://localhost").HTTP.Transport != nil {
+		t.Error("AgentClient without options has a custom transport")
+	}
+}
+
+func TestWithTunedTransport(t *testing.T) {
+	client := NewAgentClient("http://localhost", WithTunedTransport())
+
+	transport, ok := client.HTTP.Transport.(*http.Transport)
+	if !ok {
+		t.Fatalf("Transport = %T, want *http.Transport", client.HTTP.Transport)
+	}
+	if transport.MaxIdleConnsPerHost != tunedMaxIdleConnsPerHost {
+		t.Errorf("MaxIdleConnsPerHost = %v, want %v", transport.MaxIdleConnsPerHost, tunedMaxIdleConnsPerHost)
+	}
+	if transport.IdleConnTimeout != tunedIdleConnTimeout {
+		t.Errorf("IdleConnTimeout = %v, want %v", transport.IdleConnTimeout, tunedIdleConnTimeout)
+	}
+}
+
+func TestTunedTransport_ReusesConnections(t *testing.T) {
+	conns := 0
+	ts := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	ts.Config.ConnState = func(_ net.Conn, state http.ConnState) {
+		if state == http.StateNew {
+			conns++
+		}
+	}
+	ts.Start()
+	defer ts.Close()
+
+	client := NewAgentClient(ts.URL, WithTunedTransport())
+	for i := 0; i < 5; i++ {
+		if err := client.JoinSession("session-1", "valid-token"); err != nil {
+			t.Fatalf("JoinSession() error = %v", err)
+		}
+	}
+
+	if conns != 1 {
+		t.Errorf("server saw %d connections, want 1", conns)
+	}
+}
+
+func TestWithUserAgentAndHeader(t *testing.T) {
+	var got []http.Header
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = append(got, r.Header.Clone())
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`[]`))
+	}))
+	defer ts.Close()
+
+	opts := []ClientOption{
+		WithUserAgent("my-agent/2.0"),
+		WithHeader("X-Tenant-Id", "tenant-1"),
+		WithHeader("Content-Type", "text/plain"),
+	}
+	agentClient := NewAgentClient(ts.URL, opts...)
+	agentClient.SetToken("valid-token")
+	if _, err := agentClient.ListOffers(context.Background()); err != nil {
+		t.Fatalf("ListOffers() error = %v", err)
+	}
+	if err := agentClient.JoinSession("session-1", "valid-token"); err != nil {
+		t.Fatalf("JoinSession() error = %v", err)
+	}
+	if err := NewOrgClient(ts.URL, opts...).Ping(context.Background()); err != nil {
+		t.Fatalf("Ping() error = %v", err)
+	}
+
+	for i, header := range got {
+		if ua := header.Get("User-Agent"); ua != "my-agent/2.0" {
+			t.Errorf("request %d User-Agent = %q, want %q", i, ua, "my-agent/2.0")
+		}
+		if tenant := header.Get("X-Tenant-Id"); tenant != "tenant-1" {
+			t.Errorf("request %d X-Tenant-Id = %q, want %q", i, tenant, "tenant-1")
+		}
+	}
+
+	// The client's own headers win over custom ones
+	if ct := got[1].Get("Content-Type"); ct != "application/json" {
+		t.Errorf("Content-Type = %q, want %q", ct, "application/json")
+	}
+}
+
+func TestDefaultUserAgent(t *testing.T) {
+	var got string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r.Header.Get("User-Agent")
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer ts.Close()
+
+	// Clients built without the constructor get the default too
+	client := &AgentClient{BaseURL: ts.URL, HTTP: &http.Client{}}
+	if err := client.Ping(context.Background()); err != nil {
+		t.Fatalf("Ping() error = %v", err)
+	}
+	if got != DefaultUserAgent {
+		t.Errorf("User-Agent = %q, want %q", got, DefaultUserAgent)
+	}
+}
+
+// newClientCert returns a self-signed client certificate and a pool that
+// trusts it
+func newClientCert(t *testing.T) (tls.Certificate, *x509.CertPool) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("GenerateKey() error = %v", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "agent-1"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("CreateCertificate() error = %v", err)
+	}
+	leaf, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatalf("ParseCertificate() error = %v", err)
+	}
+
+	pool := x509.NewCertPool()
+	pool.AddCert(leaf)
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key, Leaf: leaf}, pool
+}
+
+func TestWithClientCert(t *testing.T) {
+	cert, clientCAs := newClientCert(t)
+
+	var gotCN string
+	ts := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotCN = r.TLS.PeerCertificates[0].Subject.CommonName
+		w.WriteHeader(http.StatusOK)
+	}))
+	ts.TLS = &tls.Config{ClientAuth: tls.RequireAndVerifyClientCert, ClientCAs: clientCAs}
+	// The failed handshake below is expected; keep it out of the test output
+	ts.Config.ErrorLog = log.New(io.Discard, "", 0)
+	ts.StartTLS()
+	defer ts.Close()
+
+	rootCAs := x509.NewCertPool()
+	rootCAs.AddCert(ts.Certificate())
+
+	tests := []struct {
+		name string
+		opts []ClientOption
+	}{
+		{name: "default transport", opts: []ClientOption{WithClientCert(cert), WithRootCAs(rootCAs)}},
+		{name: "tuned transport first", opts: []ClientOption{WithTunedTransport(), WithClientCert(cert), WithRootCAs(rootCAs)}},
+		{name: "tuned transport last", opts: []ClientOption{WithClientCert(cert), WithRootCAs(rootCAs), WithTunedTransport()}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotCN = ""
+			if err := NewAgentClient(ts.URL, tt.opts...).Ping(context.Background()); err != nil {
+				t.Fatalf("Ping() error = %v", err)
+			}
+			if gotCN != "agent-1" {
+				t.Errorf("client certificate CN = %q, want %q", gotCN, "agent-1")
+			}
+			if err := NewOrgClient(ts.URL, tt.opts...).Ping(context.Background()); err != nil {
+				t.Errorf("OrgClient Ping() error = %v", err)
+			}
+		})
+	}
+
+	// Without the certificate the handshake fails
+	if err := NewAgentClient(ts.URL, WithRootCAs(rootCAs)).Ping(context.Background()); err == nil {
+		t.Error("Ping() without client certificate error = nil, want error")
+	}
+}
+
+func TestWithClientCert_KeepsGivenTransport(t *testing.T) {
+	cert, _ := newClientCert(t)
+	transport := &http.Transport{}
+
+	client := NewAgentClient("https://localhost", WithTransport(transport), WithClientCert(cert))
+	if client.HTTP.Transport == transport {
+		t.Fatal("client uses the given transport, want a copy")
+	}
+	if transport.TLSClientConfig != nil && len(transport.TLSClientConfig.Certificates) != 0 {
+		t.Error("client certificate was added to the given transport")
+	}
+	if got := client.HTTP.Transport.(*http.Transport).TLSClientConfig.Certificates; len(got) != 1 {
+		t.Errorf("Certificates = %d, want 1", len(got))
+	}
+}
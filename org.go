@@ -1,16 +1,24 @@
 package atoa
 
 import (
+	"crypto"
 	"crypto/ecdsa"
 	"crypto/rand"
+	"crypto/rsa"
 	"crypto/sha256"
 	"crypto/x509"
 	"encoding/base64"
 	"encoding/pem"
 	"errors"
 	"fmt"
+	"strconv"
+	"sync"
+	"time"
 )
 
+// challengeNonceBytes is the amount of randomness in a challenge nonce
+const challengeNonceBytes = 32
+
 // OrgCard represents an organization's identity and verification status
 type OrgCard struct {
 	OrgID     string `json:"org_id"`
@@ -22,29 +30,27 @@ type OrgCard struct {
 
 // Validate checks if the OrgCard has all required fields and valid public key
 func (oc *OrgCard) Validate() error {
+	var errs ValidationErrors
 	if oc.OrgID == "" {
-		return errors.New("org_id is required")
+		errs.add("org_id", "is required")
 	}
 	if oc.Name == "" {
-		return errors.New("name is required")
+		errs.add("name", "is required")
 	}
 	if oc.Domain == "" {
-		return errors.New("domain is required")
-	}
-	if oc.PublicKey == "" {
-		return errors.New("public_key is required")
+		errs.add("domain", "is required")
 	}
 
 	// Validate public key format
-	block, _ := pem.Decode([]byte(oc.PublicKey))
-	if block == nil {
-		return errors.New("invalid public key format")
-	}
-	if block.Type != "PUBLIC KEY" {
-		return errors.New("public key must be in PEM format")
+	if oc.PublicKey == "" {
+		errs.add("public_key", "is required")
+	} else if block, _ := pem.Decode([]byte(oc.PublicKey)); block == nil {
+		errs.add("public_key", "has an invalid format")
+	} else if block.Type != "PUBLIC KEY" {
+		errs.add("public_key", "must be a PEM-encoded PUBLIC KEY")
 	}
 
-	return nil
+	return errs.err()
 }
 
 // SignChallenge signs the given challenge using the provided private key
@@ -57,6 +63,17 @@ func SignChallenge(challenge string, privateKey *ecdsa.PrivateKey) (string, erro
 	return base64.StdEncoding.EncodeToString(signature), nil
 }
 
+// SignChallengeRSA signs the given challenge with an RSA private key using
+// PKCS #1 v1.5 over SHA-256
+func SignChallengeRSA(challenge string, privateKey *rsa.PrivateKey) (string, error) {
+	hash := sha256.Sum256([]byte(challenge))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, privateKey, crypto.SHA256, hash[:])
+	if err != nil {
+		return "", fmt.Errorf("failed to sign challenge: %w", err)
+	}
+	return base64.StdEncoding.EncodeToString(signature), nil
+}
+
 // VerifySignature verifies a signature against a challenge using the public key
 func VerifySignature(challenge, signature, publicKeyPEM string) (bool, error) {
 	block, _ := pem.Decode([]byte(publicKeyPEM))
@@ -75,20 +92,167 @@ func VerifySignature(challenge, signature, publicKeyPEM string) (bool, error) {
 	}
 
 	hash := sha256.Sum256([]byte(challenge))
-	return ecdsa.VerifyASN1(pubKey, hash[:], sig), nil
+	return pubKey.verify(hash[:], sig), nil
+}
+
+// Challenge is a time-limited value an organization signs to prove possession
+// of its private key. It is meant to be answered once: verify answers with
+// VerifyChallengeSignatureOnce to reject replayed signatures.
+type Challenge struct {
+	// Value is the exact string the server asked to have signed. Servers
+	// that return only a bare string set nothing else.
+	Value     string    `json:"challenge,omitempty"`
+	Nonce     string    `json:"nonce"`
+	IssuedAt  time.Time `json:"issued_at"`
+	ExpiresAt time.Time `json:"expires_at"`
+	// Algorithm is the JWT name of the signature algorithm the platform
+	// expects, ES256 or RS256. Empty means the platform did not say.
+	Algorithm string `json:"algorithm,omitempty"`
+}
+
+// NewChallenge generates a challenge with a random nonce that expires after ttl
+func NewChallenge(ttl time.Duration) (Challenge, error) {
+	if ttl <= 0 {
+		return Challenge{}, errors.New("challenge ttl must be positive")
+	}
+
+	nonce := make([]byte, challengeNonceBytes)
+	if _, err := rand.Read(nonce); err != nil {
+		return Challenge{}, fmt.Errorf("failed to generate nonce: %w", err)
+	}
+
+	now := timeNow()
+	return Challenge{
+		Nonce:     base64.RawURLEncoding.EncodeToString(nonce),
+		IssuedAt:  now,
+		ExpiresAt: now.Add(ttl),
+	}, nil
+}
+
+// String returns the value that is signed for the challenge: Value when the
+// server sent one. Otherwise, for challenges made with NewChallenge, it binds
+// the nonce to the issue time so neither can be swapped independently, and a
+// challenge without an issue time is the nonce itself.
+func (ch Challenge) String() string {
+	if ch.Value != "" {
+		return ch.Value
+	}
+	if ch.IssuedAt.IsZero() {
+		return ch.Nonce
+	}
+	return ch.Nonce + "." + strconv.FormatInt(ch.IssuedAt.Unix(), 10)
+}
+
+// Expired reports whether the challenge can no longer be answered. A
+// challenge without an expiry never expires on the client side.
+func (ch Challenge) Expired() bool {
+	if ch.ExpiresAt.IsZero() {
+		return false
+	}
+	return !timeNow().Before(ch.ExpiresAt)
 }
 
-// parsePublicKey parses a DER-encoded public key
-func parsePublicKey(der []byte) (*ecdsa.PublicKey, error) {
+// VerifyChallengeSignature verifies a signature produced by SignChallenge over
+// ch.String(), rejecting challenges that have expired. It does not stop a
+// captured signature from being replayed until the challenge expires; use
+// VerifyChallengeSignatureOnce for that.
+func VerifyChallengeSignature(ch Challenge, signature, publicKeyPEM string) (bool, error) {
+	if ch.Nonce == "" && ch.Value == "" {
+		return false, errors.New("challenge nonce is required")
+	}
+	if ch.Expired() {
+		return false, ErrChallengeExpired
+	}
+	return VerifySignature(ch.String(), signature, publicKeyPEM)
+}
+
+// VerifyChallengeSignatureOnce is like VerifyChallengeSignature, and also
+// records each validly answered challenge in store. A challenge that was
+// already answered fails with ErrChallengeUsed. Invalid signatures do not use
+// up the challenge.
+func VerifyChallengeSignatureOnce(store NonceStore, ch Challenge, signature, publicKeyPEM string) (bool, error) {
+	if store == nil {
+		return false, errors.New("nonce store is required")
+	}
+	valid, err := VerifyChallengeSignature(ch, signature, publicKeyPEM)
+	if err != nil || !valid {
+		return valid, err
+	}
+	if !store.Use(ch.String(), ch.ExpiresAt) {
+		return false, ErrChallengeUsed
+	}
+	return true, nil
+}
+
+// NonceStore remembers answered challenges for VerifyChallengeSignatureOnce.
+// Implementations must be safe for concurrent use; servers running more than
+// one instance need a shared store.
+type NonceStore interface {
+	// Use marks nonce as used and reports whether it was unused before.
+	// The nonce only needs to be remembered until expiresAt, or forever when
+	// expiresAt is zero.
+	Use(nonce string, expiresAt time.Time) bool
+}
+
+// MemoryNonceStore is an in-process NonceStore. It forgets nonces once they
+// have expired.
+type MemoryNonceStore struct {
+	mu   sync.Mutex
+	used map[string]time.Time
+}
+
+// NewMemoryNonceStore creates an empty MemoryNonceStore
+func NewMemoryNonceStore() *MemoryNonceStore {
+	return &MemoryNonceStore{used: make(map[string]time.Time)}
+}
+
+// Use implements NonceStore
+func (s *MemoryNonceStore) Use(nonce string, expiresAt time.Time) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := timeNow()
+	for used, expiry := range s.used {
+		if !expiry.IsZero() && !now.Before(expiry) {
+			delete(s.used, used)
+		}
+	}
+
+	if _, ok := s.used[nonce]; ok {
+		return false
+	}
+	s.used[nonce] = expiresAt
+	return true
+}
+
+// publicKey wraps a parsed public key of one of the supported types
+type publicKey struct {
+	ecdsa *ecdsa.PublicKey
+	rsa   *rsa.PublicKey
+}
+
+// verify checks a signature over a SHA-256 hash: ASN.1 ECDSA for EC keys and
+// PKCS #1 v1.5 for RSA keys
+func (k *publicKey) verify(hash, sig []byte) bool {
+	if k.rsa != nil {
+		return rsa.VerifyPKCS1v15(k.rsa, crypto.SHA256, hash, sig) == nil
+	}
+	return ecdsa.VerifyASN1(k.ecdsa, hash, sig)
+}
+
+// parsePublicKey parses a DER-encoded ECDSA or RSA public key
+func parsePublicKey(der []byte) (*publicKey, error) {
 	pub, err := x509.ParsePKIXPublicKey(der)
 	if err != nil {
 		return nil, fmt.Errorf("failed to parse public key: %w", err)
 	}
 
-	ecdsaPub, ok := pub.(*ecdsa.PublicKey)
-	if !ok {
-		return nil, errors.New("public key is not an ECDSA key")
+	switch key := pub.(type) {
+	case *ecdsa.PublicKey:
+		return &publicKey{ecdsa: key}, nil
+	case *rsa.PublicKey:
+		return &publicKey{rsa: key}, nil
+	default:
+		return nil, fmt.Errorf("unsupported public key type %T", pub)
 	}
-
-	return ecdsaPub, nil
 }
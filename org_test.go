@@ -1,14 +1,19 @@
 package atoa
 
 import (
+	"context"
 	"crypto/ecdsa"
 	"crypto/elliptic"
 	"crypto/rand"
+	"crypto/rsa"
 	"crypto/x509"
+	"encoding/json"
 	"encoding/pem"
+	"errors"
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 )
 
 func TestOrgCard_Validate(t *testing.T) {
@@ -86,7 +91,7 @@ func TestOrgClient_RegisterOrg(t *testing.T) {
 	if err != nil {
 		t.Errorf("RegisterOrg() error = %v", err)
 	}
-	if challenge != "test-challenge" {
+	if challenge.String() != "test-challenge" {
 		t.Errorf("RegisterOrg() challenge = %v, want %v", challenge, "test-challenge")
 	}
 }
@@ -117,6 +122,166 @@ func TestOrgClient_RequestToken(t *testing.T) {
 	}
 }
 
+func TestVerifyChallengeSignature(t *testing.T) {
+	privateKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate private key: %v", err)
+	}
+	publicKeyPEM, err := MarshalPublicKeyPEM(&privateKey.PublicKey)
+	if err != nil {
+		t.Fatalf("failed to marshal public key: %v", err)
+	}
+
+	ch, err := NewChallenge(time.Minute)
+	if err != nil {
+		t.Fatalf("NewChallenge() error = %v", err)
+	}
+	signature, err := SignChallenge(ch.String(), privateKey)
+	if err != nil {
+		t.Fatalf("SignChallenge() error = %v", err)
+	}
+
+	ok, err := VerifyChallengeSignature(ch, signature, publicKeyPEM)
+	if err != nil || !ok {
+		t.Errorf("VerifyChallengeSignature() = %v, %v, want true, nil", ok, err)
+	}
+
+	// A fresh challenge has a different nonce, so the old signature is useless
+	other, err := NewChallenge(time.Minute)
+	if err != nil {
+		t.Fatalf("NewChallenge() error = %v", err)
+	}
+	if other.Nonce == ch.Nonce {
+		t.Error("NewChallenge() returned a repeated nonce")
+	}
+	ok, err = VerifyChallengeSignature(other, signature, publicKeyPEM)
+	if err != nil || ok {
+		t.Errorf("VerifyChallengeSignature() with replayed signature = %v, %v, want false, nil", ok, err)
+	}
+
+	// Expired challenges are rejected even with a valid signature
+	expired := ch
+	expired.ExpiresAt = time.Now().Add(-time.Second)
+	_, err = VerifyChallengeSignature(expired, signature, publicKeyPEM)
+	if !errors.Is(err, ErrChallengeExpired) {
+		t.Errorf("VerifyChallengeSignature() error = %v, want %v", err, ErrChallengeExpired)
+	}
+
+	if _, err := NewChallenge(0); err == nil {
+		t.Error("NewChallenge(0) error = nil, want error")
+	}
+}
+
+func TestVerifyChallengeSignatureOnce(t *testing.T) {
+	privateKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate private key: %v", err)
+	}
+	publicKeyPEM, err := MarshalPublicKeyPEM(&privateKey.PublicKey)
+	if err != nil {
+		t.Fatalf("failed to marshal public key: %v", err)
+	}
+
+	ch, err := NewChallenge(time.Minute)
+	if err != nil {
+		t.Fatalf("NewChallenge() error = %v", err)
+	}
+	signature, err := SignChallenge(ch.String(), privateKey)
+	if err != nil {
+		t.Fatalf("SignChallenge() error = %v", err)
+	}
+
+	store := NewMemoryNonceStore()
+
+	// A bad signature does not use up the challenge
+	ok, err := VerifyChallengeSignatureOnce(store, ch, "bm90LWEtc2lnbmF0dXJl", publicKeyPEM)
+	if ok {
+		t.Errorf("VerifyChallengeSignatureOnce() with bad signature = %v, %v, want false", ok, err)
+	}
+
+	ok, err = VerifyChallengeSignatureOnce(store, ch, signature, publicKeyPEM)
+	if err != nil || !ok {
+		t.Fatalf("VerifyChallengeSignatureOnce() = %v, %v, want true, nil", ok, err)
+	}
+
+	// Replaying the same answer fails
+	ok, err = VerifyChallengeSignatureOnce(store, ch, signature, publicKeyPEM)
+	if ok || !errors.Is(err, ErrChallengeUsed) {
+		t.Errorf("VerifyChallengeSignatureOnce() replayed = %v, %v, want false, %v", ok, err, ErrChallengeUsed)
+	}
+
+	if _, err := VerifyChallengeSignatureOnce(nil, ch, signature, publicKeyPEM); err == nil {
+		t.Error("VerifyChallengeSignatureOnce() with nil store error = nil, want error")
+	}
+}
+
+func TestMemoryNonceStore_ForgetsExpired(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	defer SetTimeFunc(func() time.Time { return now })()
+
+	store := NewMemoryNonceStore()
+	if !store.Use("nonce", now.Add(time.Minute)) {
+		t.Fatal("Use() of a fresh nonce = false, want true")
+	}
+	if store.Use("nonce", now.Add(time.Minute)) {
+		t.Error("Use() of a used nonce = true, want false")
+	}
+
+	now = now.Add(2 * time.Minute)
+	store.Use("other", time.Time{})
+	if len(store.used) != 1 {
+		t.Errorf("store holds %d nonces after expiry, want 1", len(store.used))
+	}
+}
+
+func TestVerifySignature_RSA(t *testing.T) {
+	privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("failed to generate private key: %v", err)
+	}
+	publicKeyBytes, err := x509.MarshalPKIXPublicKey(&privateKey.PublicKey)
+	if err != nil {
+		t.Fatalf("failed to marshal public key: %v", err)
+	}
+	publicKeyPEM := string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: publicKeyBytes}))
+
+	signature, err := SignChallengeRSA("test-challenge", privateKey)
+	if err != nil {
+		t.Fatalf("SignChallengeRSA() error = %v", err)
+	}
+
+	ok, err := VerifySignature("test-challenge", signature, publicKeyPEM)
+	if err != nil || !ok {
+		t.Errorf("VerifySignature() = %v, %v, want true, nil", ok, err)
+	}
+
+	ok, err = VerifySignature("other-challenge", signature, publicKeyPEM)
+	if err != nil || ok {
+		t.Errorf("VerifySignature() with wrong challenge = %v, %v, want false, nil", ok, err)
+	}
+}
+
+func TestOrgClient_RequestTokenContext_Deadline(t *testing.T) {
+	release := make(chan struct{})
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	}))
+	defer ts.Close()
+	defer close(release)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	client := NewOrgClient(ts.URL)
+	_, err := client.RequestTokenContext(ctx, "test-org", "test-challenge", "test-signature")
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("RequestTokenContext() error = %v, want %v", err, context.DeadlineExceeded)
+	}
+}
+
 // Helper function to generate a test public key
 func generateTestPublicKey(t *testing.T) string {
 	privateKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
@@ -124,15 +289,90 @@ func generateTestPublicKey(t *testing.T) string {
 		t.Fatalf("failed to generate private key: %v", err)
 	}
 
-	publicKeyBytes, err := x509.MarshalPKIXPublicKey(&privateKey.PublicKey)
+	publicKeyPEM, err := MarshalPublicKeyPEM(&privateKey.PublicKey)
 	if err != nil {
 		t.Fatalf("failed to marshal public key: %v", err)
 	}
 
-	publicKeyPEM := pem.EncodeToMemory(&pem.Block{
-		Type:  "PUBLIC KEY",
-		Bytes: publicKeyBytes,
+	return publicKeyPEM
+}
+
+func TestOrgClient_RegisterOrg_StructuredChallenge(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{
+			"challenge": "opaque-server-challenge",
+			"nonce": "abc",
+			"issued_at": "2023-11-14T22:13:20Z",
+			"expires_at": "2023-11-14T22:18:20Z",
+			"algorithm": "ES256"
+		}`))
+	}))
+	defer ts.Close()
+
+	challenge, err := NewOrgClient(ts.URL).RegisterOrg(&OrgCard{
+		OrgID:     "test-org",
+		Name:      "Test Org",
+		Domain:    "test.org",
+		PublicKey: generateTestPublicKey(t),
 	})
+	if err != nil {
+		t.Fatalf("RegisterOrg() error = %v", err)
+	}
 
-	return string(publicKeyPEM)
+	if challenge.Nonce != "abc" {
+		t.Errorf("Nonce = %v, want %v", challenge.Nonce, "abc")
+	}
+	if challenge.Algorithm != "ES256" {
+		t.Errorf("Algorithm = %v, want %v", challenge.Algorithm, "ES256")
+	}
+	if want := time.Unix(1700000300, 0); !challenge.ExpiresAt.Equal(want) {
+		t.Errorf("ExpiresAt = %v, want %v", challenge.ExpiresAt, want)
+	}
+	// The server's challenge string is signed as is
+	if challenge.String() != "opaque-server-challenge" {
+		t.Errorf("String() = %v, want %v", challenge.String(), "opaque-server-challenge")
+	}
+}
+
+func TestOrgClient_RequestTokenForChallenge(t *testing.T) {
+	var sent string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Challenge string `json:"challenge"`
+		}
+		json.NewDecoder(r.Body).Decode(&req)
+		sent = req.Challenge
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"token": "test-token"}`))
+	}))
+	defer ts.Close()
+
+	client := NewOrgClient(ts.URL)
+	challenge := Challenge{
+		Value:     "opaque-server-challenge",
+		Nonce:     "abc",
+		IssuedAt:  time.Now(),
+		ExpiresAt: time.Now().Add(time.Minute),
+	}
+	token, err := client.RequestTokenForChallenge("test-org", challenge, "test-signature")
+	if err != nil {
+		t.Fatalf("RequestTokenForChallenge() error = %v", err)
+	}
+	if token != "test-token" {
+		t.Errorf("token = %v, want %v", token, "test-token")
+	}
+	if sent != "opaque-server-challenge" {
+		t.Errorf("sent challenge = %v, want %v", sent, "opaque-server-challenge")
+	}
+
+	// Expired challenges are not sent
+	sent = ""
+	challenge.ExpiresAt = time.Now().Add(-time.Second)
+	if _, err := client.RequestTokenForChallenge("test-org", challenge, "test-signature"); !errors.Is(err, ErrChallengeExpired) {
+		t.Errorf("RequestTokenForChallenge() error = %v, want %v", err, ErrChallengeExpired)
+	}
+	if sent != "" {
+		t.Error("expired challenge was sent to the server")
+	}
 }
@@ -0,0 +1,105 @@
+package atoa
+
+import (
+	"context"
+	"fmt"
+	"sort"
+)
+
+// ScoredOffer is an offer ranked by RecommendOffers
+type ScoredOffer struct {
+	Offer Offer
+	Score float64
+}
+
+// OfferScorer rates how well an offer suits an agent with the given
+// capabilities; higher is better. It is only called for offers the agent
+// meets the requirements of.
+type OfferScorer func(offer Offer, capabilities []string) float64
+
+// ScoreWeights weighs the parts of the default offer score
+type ScoreWeights struct {
+	// Capabilities weighs the share of the agent's capabilities the offer
+	// makes use of
+	Capabilities float64
+	// VersionHeadroom weighs offers whose min_version is below
+	// ProtocolVersion, or unset, over offers that need exactly
+	// ProtocolVersion
+	VersionHeadroom float64
+}
+
+// DefaultScoreWeights are the weights used when AgentClient.OfferScorer is nil
+var DefaultScoreWeights = ScoreWeights{Capabilities: 1, VersionHeadroom: 0.25}
+
+// Scorer returns an OfferScorer that applies the weights
+func (w ScoreWeights) Scorer() OfferScorer {
+	return func(offer Offer, capabilities []string) float64 {
+		return ScoreOffer(offer, capabilities, w)
+	}
+}
+
+// ScoreOffer scores an offer for an agent with the given capabilities: the
+// fraction of the agent's distinct capabilities the offer requires times
+// w.Capabilities, plus w.VersionHeadroom if the offer does not require
+// exactly ProtocolVersion
+func ScoreOffer(offer Offer, capabilities []string, w ScoreWeights) float64 {
+	var score float64
+
+	have := make(map[string]bool, len(capabilities))
+	for _, capability := range capabilities {
+		have[capability] = true
+	}
+	if len(have) > 0 {
+		used := len(NegotiateCapabilities(offer.Requirements.Capabilities, capabilities))
+		score += w.Capabilities * float64(used) / float64(len(have))
+	}
+
+	if offer.Requirements.MinVersion == "" {
+		score += w.VersionHeadroom
+	} else if cmp, err := compareVersions(ProtocolVersion, offer.Requirements.MinVersion); err == nil && cmp > 0 {
+		score += w.VersionHeadroom
+	}
+
+	return score
+}
+
+// RecommendOffers lists the offers this agent meets the requirements of,
+// scored with OfferScorer against the capabilities in the agent token, and
+// returns at most max of them sorted by descending score. Equal scores are
+// ordered by offer ID. A max of zero or less returns every match.
+func (c *AgentClient) RecommendOffers(ctx context.Context, max int) ([]ScoredOffer, error) {
+	claims, err := PeekAgentClaims(c.Token())
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode agent token: %w", err)
+	}
+
+	offers, err := c.ListOffers(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	scorer := c.OfferScorer
+	if scorer == nil {
+		scorer = DefaultScoreWeights.Scorer()
+	}
+
+	scored := []ScoredOffer{}
+	for _, offer := range offers {
+		if offer.Requirements.Check(claims.Capabilities, c.matchOptions()...) != nil {
+			continue
+		}
+		scored = append(scored, ScoredOffer{Offer: offer, Score: scorer(offer, claims.Capabilities)})
+	}
+
+	sort.Slice(scored, func(i, j int) bool {
+		if scored[i].Score != scored[j].Score {
+			return scored[i].Score > scored[j].Score
+		}
+		return scored[i].Offer.Header.ID < scored[j].Offer.Header.ID
+	})
+
+	if max > 0 && len(scored) > max {
+		scored = scored[:max]
+	}
+	return scored, nil
+}
@@ -0,0 +1,128 @@
+package atoa
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+const testRecommendOffers = `[
+	{"header": {"id": "offer-c", "title": "C", "type": "service"}, "requirements": {"capabilities": [], "min_version": "0.9"}},
+	{"header": {"id": "offer-e", "title": "E", "type": "service"}, "requirements": {"capabilities": ["file"]}},
+	{"header": {"id": "offer-d", "title": "D", "type": "service"}, "requirements": {"capabilities": ["image"]}},
+	{"header": {"id": "offer-b", "title": "B", "type": "service"}, "requirements": {"capabilities": ["text"]}},
+	{"header": {"id": "offer-a", "title": "A", "type": "service"}, "requirements": {"capabilities": ["text", "file"], "min_version": "1.0"}}
+]`
+
+// newRecommendClient returns a client for an agent with the "text" and
+// "file" capabilities, served testRecommendOffers
+func newRecommendClient(t *testing.T) *AgentClient {
+	t.Helper()
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(testRecommendOffers))
+	}))
+	t.Cleanup(ts.Close)
+
+	orgToken, err := IssueOrgToken("test-org", true, testPrivateKey)
+	if err != nil {
+		t.Fatalf("failed to issue org token: %v", err)
+	}
+	token, err := IssueAgentToken(&AgentCard{
+		AgentID:      "agent-1",
+		OrgID:        "test-org",
+		Capabilities: []string{"text", "file"},
+	}, orgToken, testPrivateKey)
+	if err != nil {
+		t.Fatalf("failed to issue agent token: %v", err)
+	}
+
+	client := &AgentClient{
+		BaseURL: ts.URL,
+		HTTP:    &http.Client{},
+	}
+	client.SetToken(token)
+	return client
+}
+
+// scoredIDs returns the offer IDs of scored offers in order
+func scoredIDs(scored []ScoredOffer) []string {
+	ids := make([]string, len(scored))
+	for i, s := range scored {
+		ids[i] = s.Offer.Header.ID
+	}
+	return ids
+}
+
+func TestRecommendOffers(t *testing.T) {
+	client := newRecommendClient(t)
+
+	tests := []struct {
+		name       string
+		max        int
+		wantIDs    []string
+		wantScores []float64
+	}{
+		{
+			name:       "all matches",
+			max:        0,
+			wantIDs:    []string{"offer-a", "offer-b", "offer-e", "offer-c"},
+			wantScores: []float64{1, 0.75, 0.75, 0.25},
+		},
+		{
+			name:       "top two",
+			max:        2,
+			wantIDs:    []string{"offer-a", "offer-b"},
+			wantScores: []float64{1, 0.75},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			scored, err := client.RecommendOffers(context.Background(), tt.max)
+			if err != nil {
+				t.Fatalf("RecommendOffers() error = %v", err)
+			}
+			if got := scoredIDs(scored); !reflect.DeepEqual(got, tt.wantIDs) {
+				t.Fatalf("ids = %v, want %v", got, tt.wantIDs)
+			}
+			for i, s := range scored {
+				if s.Score != tt.wantScores[i] {
+					t.Errorf("%s score = %v, want %v", s.Offer.Header.ID, s.Score, tt.wantScores[i])
+				}
+			}
+		})
+	}
+}
+
+func TestRecommendOffers_CustomScorer(t *testing.T) {
+	client := newRecommendClient(t)
+
+	// Weighing only version headroom ranks the offer that needs exactly
+	// ProtocolVersion last
+	client.OfferScorer = ScoreWeights{VersionHeadroom: 1}.Scorer()
+	scored, err := client.RecommendOffers(context.Background(), 0)
+	if err != nil {
+		t.Fatalf("RecommendOffers() error = %v", err)
+	}
+	want := []string{"offer-b", "offer-c", "offer-e", "offer-a"}
+	if got := scoredIDs(scored); !reflect.DeepEqual(got, want) {
+		t.Errorf("ids = %v, want %v", got, want)
+	}
+
+	client.OfferScorer = func(offer Offer, capabilities []string) float64 {
+		if offer.Header.ID == "offer-c" {
+			return 10
+		}
+		return 0
+	}
+	scored, err = client.RecommendOffers(context.Background(), 1)
+	if err != nil {
+		t.Fatalf("RecommendOffers() error = %v", err)
+	}
+	if got := scoredIDs(scored); !reflect.DeepEqual(got, []string{"offer-c"}) {
+		t.Errorf("ids = %v, want [offer-c]", got)
+	}
+}
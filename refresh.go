@@ -0,0 +1,114 @@
+package atoa
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+const (
+	// defaultRefreshMargin is how long before expiry the token is refreshed.
+	// Short-lived tokens are refreshed after 90% of their lifetime instead.
+	defaultRefreshMargin = 1 * time.Minute
+	// minRefreshBackoff is the first delay after a failed refresh
+	minRefreshBackoff = 1 * time.Second
+	// maxRefreshBackoff caps the delay between failed refresh attempts
+	maxRefreshBackoff = 1 * time.Minute
+)
+
+// RefreshToken obtains a new agent token by registering the client's
+// AgentCard again with c.OrgToken, and stores it with SetToken
+func (c *AgentClient) RefreshToken(ctx context.Context) (string, error) {
+	if c.OrgToken == "" {
+		return "", errors.New("org token is not set on the client")
+	}
+
+	card := c.Card()
+	token, err := c.RegisterAgentContext(ctx, &card, c.OrgToken)
+	if err != nil {
+		return "", err
+	}
+
+	c.SetToken(token)
+	return token, nil
+}
+
+// StartAutoRefresh starts a background loop that refreshes the agent token
+// shortly before it expires, retrying with exponential backoff on failure. A
+// new token that cannot be decoded or has already expired counts as a failure.
+// The loop stops when ctx is canceled or the client is closed.
+func (c *AgentClient) StartAutoRefresh(ctx context.Context) {
+	go c.autoRefresh(ctx)
+}
+
+// LastRefreshError returns the result of the most recent background refresh
+// attempt, or nil if it succeeded or none has run yet
+func (c *AgentClient) LastRefreshError() error {
+	c.refreshMu.Lock()
+	defer c.refreshMu.Unlock()
+	return c.lastRefreshErr
+}
+
+// autoRefresh runs the refresh loop started by StartAutoRefresh
+func (c *AgentClient) autoRefresh(ctx context.Context) {
+	lifetime := c.lifetime()
+	backoff := minRefreshBackoff
+	// A current token that cannot be scheduled is refreshed immediately
+	wait, _ := c.untilRefresh()
+
+	for {
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-lifetime.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+
+		_, err := c.RefreshToken(ctx)
+		if err == nil {
+			// A new token that cannot be scheduled would be refreshed again
+			// at once, so it is handled like a failed refresh
+			wait, err = c.untilRefresh()
+		}
+		c.refreshMu.Lock()
+		c.lastRefreshErr = err
+		c.refreshMu.Unlock()
+
+		if err != nil {
+			wait = backoff
+			backoff = min(backoff*2, maxRefreshBackoff)
+			continue
+		}
+		backoff = minRefreshBackoff
+		wait = max(wait, minRefreshBackoff)
+	}
+}
+
+// untilRefresh returns how long to wait before refreshing the current token.
+// It fails for tokens that cannot be decoded, carry no expiry or have already
+// expired.
+func (c *AgentClient) untilRefresh() (time.Duration, error) {
+	claims, err := PeekAgentClaims(c.Token())
+	if err != nil {
+		return 0, fmt.Errorf("cannot schedule token refresh: %w", err)
+	}
+	if claims.ExpiresAt == nil {
+		return 0, errors.New("cannot schedule token refresh: token has no expiry")
+	}
+
+	expiresAt := claims.ExpiresAt.Time
+	if time.Until(expiresAt) <= 0 {
+		return 0, errors.New("cannot schedule token refresh: token is expired")
+	}
+	margin := defaultRefreshMargin
+	if claims.IssuedAt != nil {
+		margin = min(margin, expiresAt.Sub(claims.IssuedAt.Time)/10)
+	}
+
+	return time.Until(expiresAt.Add(-margin)), nil
+}
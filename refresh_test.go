@@ -0,0 +1,196 @@
+package atoa
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+func TestAgentClient_StartAutoRefresh(t *testing.T) {
+	var refreshes int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/agents/token" {
+			t.Errorf("expected path /agents/token, got %s", r.URL.Path)
+		}
+		atomic.AddInt32(&refreshes, 1)
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{"token": "` + issueShortLivedToken(t) + `"}`))
+	}))
+	defer ts.Close()
+
+	client := NewAgentClient(ts.URL)
+	client.OrgToken = "org-token"
+	client.AgentCard = AgentCard{
+		AgentID:      "agent-1",
+		OrgID:        "test-org",
+		Capabilities: []string{"text"},
+	}
+	client.SetToken(issueShortLivedToken(t))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	client.StartAutoRefresh(ctx)
+
+	// Each token lives one second, so the loop refreshes roughly every second
+	deadline := time.Now().Add(5 * time.Second)
+	for atomic.LoadInt32(&refreshes) < 2 && time.Now().Before(deadline) {
+		time.Sleep(50 * time.Millisecond)
+	}
+	if got := atomic.LoadInt32(&refreshes); got < 2 {
+		t.Fatalf("refreshes = %d, want at least 2", got)
+	}
+	if err := client.LastRefreshError(); err != nil {
+		t.Errorf("LastRefreshError() = %v, want nil", err)
+	}
+}
+
+func TestAgentClient_StartAutoRefresh_Failure(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "org token expired", http.StatusUnauthorized)
+	}))
+	defer ts.Close()
+
+	client := NewAgentClient(ts.URL)
+	client.OrgToken = "org-token"
+	client.AgentCard = AgentCard{
+		AgentID:      "agent-1",
+		OrgID:        "test-org",
+		Capabilities: []string{"text"},
+	}
+	client.StartAutoRefresh(context.Background())
+
+	// An undecodable token is refreshed immediately
+	deadline := time.Now().Add(2 * time.Second)
+	for client.LastRefreshError() == nil && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if client.LastRefreshError() == nil {
+		t.Error("LastRefreshError() = nil, want error")
+	}
+
+	// Close stops the loop
+	client.Close()
+}
+
+func TestAgentClient_StartAutoRefresh_OpaqueToken(t *testing.T) {
+	var refreshes int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&refreshes, 1)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{"token": "opaque-token"}`))
+	}))
+	defer ts.Close()
+
+	client := NewAgentClient(ts.URL)
+	client.OrgToken = "org-token"
+	client.AgentCard = AgentCard{
+		AgentID:      "agent-1",
+		OrgID:        "test-org",
+		Capabilities: []string{"text"},
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	client.StartAutoRefresh(ctx)
+
+	// A token that cannot be scheduled goes through the backoff instead of
+	// being refreshed again at once
+	time.Sleep(500 * time.Millisecond)
+	if got := atomic.LoadInt32(&refreshes); got != 1 {
+		t.Errorf("refreshes = %d, want 1", got)
+	}
+	if client.LastRefreshError() == nil {
+		t.Error("LastRefreshError() = nil, want error")
+	}
+}
+
+// Helper function to issue an agent token that expires after one second
+func issueShortLivedToken(t *testing.T) string {
+	now := time.Now()
+	claims := AgentTokenClaims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    TokenIssuer,
+			Audience:  jwt.ClaimStrings{AgentTokenAudience},
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(time.Second)),
+		},
+		AgentID:  "agent-1",
+		OrgID:    "test-org",
+		Verified: true,
+	}
+
+	token, err := jwt.NewWithClaims(jwt.SigningMethodES256, claims).SignedString(testPrivateKey)
+	if err != nil {
+		t.Errorf("failed to sign token: %v", err)
+	}
+	return token
+}
+
+// TestAgentClient_RefreshWhileSending is meant to run under the race detector:
+// RefreshToken replaces the token while SendMessage reads it
+func TestAgentClient_RefreshWhileSending(t *testing.T) {
+	var tokens int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if r.URL.Path == "/agents/token" {
+			n := atomic.AddInt32(&tokens, 1)
+			w.Write([]byte(`{"token": "token-` + strconv.Itoa(int(n)) + `"}`))
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer ts.Close()
+
+	client := NewAgentClient(ts.URL)
+	client.OrgToken = "org-token"
+	client.AgentCard = AgentCard{
+		AgentID:      "agent-1",
+		OrgID:        "test-org",
+		Capabilities: []string{"text"},
+	}
+	client.SetToken("token-0")
+
+	const rounds = 20
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < rounds; i++ {
+			if _, err := client.RefreshToken(context.Background()); err != nil {
+				t.Errorf("RefreshToken() error = %v", err)
+			}
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		for i := 0; i < rounds; i++ {
+			err := client.SendMessage(context.Background(), A2AMessage{
+				SessionID:   "session-123",
+				FromAgentID: "agent-1",
+				ToAgentID:   "agent-2",
+				Type:        MessageTypeText,
+				Payload:     json.RawMessage(`{"content": "Hello"}`),
+				Timestamp:   time.Now(),
+			})
+			if err != nil {
+				t.Errorf("SendMessage() error = %v", err)
+			}
+		}
+	}()
+	wg.Wait()
+
+	if got, want := client.Token(), "token-"+strconv.Itoa(rounds); got != want {
+		t.Errorf("Token() = %v, want %v", got, want)
+	}
+}
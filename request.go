@@ -0,0 +1,207 @@
+package atoa
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// RetryPolicy makes a client retry idempotent requests (GET, HEAD, PUT and
+// DELETE) that fail on the network or with 429, 502, 503 or 504. A
+// Retry-After header in seconds replaces the computed delay.
+type RetryPolicy struct {
+	// MaxAttempts is the total number of attempts, including the first.
+	// Values below 2 disable retries.
+	MaxAttempts int
+	// MinBackoff is the delay before the first retry. It doubles for each
+	// further retry, up to MaxBackoff when that is set.
+	MinBackoff time.Duration
+	MaxBackoff time.Duration
+}
+
+// attempts returns how many times a request with the given method may be
+// sent under the policy
+func (p *RetryPolicy) attempts(method string) int {
+	if p == nil || p.MaxAttempts < 2 {
+		return 1
+	}
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodPut, http.MethodDelete:
+		return p.MaxAttempts
+	default:
+		return 1
+	}
+}
+
+// backoff returns the delay before the given retry, counting from 1
+func (p *RetryPolicy) backoff(retry int, resp *http.Response) time.Duration {
+	delay := p.MinBackoff
+	for i := 1; i < retry && (p.MaxBackoff <= 0 || delay < p.MaxBackoff); i++ {
+		delay *= 2
+	}
+	if resp != nil {
+		if seconds, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil && seconds >= 0 {
+			delay = time.Duration(seconds) * time.Second
+		}
+	}
+	if p.MaxBackoff > 0 && delay > p.MaxBackoff {
+		delay = p.MaxBackoff
+	}
+	return delay
+}
+
+// shouldRetry reports whether the outcome of an attempt is worth retrying
+func shouldRetry(resp *http.Response, err error) bool {
+	if err != nil {
+		return true
+	}
+	switch resp.StatusCode {
+	case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	default:
+		return false
+	}
+}
+
+// requester is what doJSON needs from a client. AgentClient and OrgClient
+// each build one, so that request encoding, auth, compression, retries and
+// error handling are shared between them. Only calls that need something
+// doJSON cannot give build their own requests: SendMessage for its codec,
+// StreamOffers for its event stream and Ping for its error mapping.
+type requester struct {
+	http                 *http.Client
+	endpoint             func(path string) string
+	setHeaders           func(req *http.Request)
+	decoder              ResponseDecoderFunc
+	compressionThreshold int
+	retry                *RetryPolicy
+}
+
+// doJSON sends a request for path, with body encoded as JSON unless it is
+// nil, and decodes a successful response into out unless it is nil.
+// Responses outside the 2xx range are returned as *APIError. Idempotent
+// requests are retried according to the retry policy.
+func (r requester) doJSON(ctx context.Context, method, path string, body, out interface{}) error {
+	var payload []byte
+	if body != nil {
+		var err error
+		payload, err = json.Marshal(body)
+		if err != nil {
+			return fmt.Errorf("failed to marshal request: %w", err)
+		}
+	}
+
+	var reqBody io.Reader
+	if payload != nil {
+		reqBody = bytes.NewReader(payload)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, r.endpoint(path), reqBody)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	r.setHeaders(req)
+	if payload != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	acceptGzip(req)
+
+	resp, err := r.send(ctx, req, payload)
+	if err != nil {
+		return fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return newAPIError(resp)
+	}
+	if out == nil || resp.StatusCode == http.StatusNoContent {
+		return nil
+	}
+
+	if err := decodeResponse(r.decoder, resp, out); err != nil {
+		return fmt.Errorf("failed to decode response: %w", err)
+	}
+	return nil
+}
+
+// send makes up to the allowed number of attempts at req, resending payload
+// each time, and returns the last outcome
+func (r requester) send(ctx context.Context, req *http.Request, payload []byte) (*http.Response, error) {
+	attempts := r.retry.attempts(req.Method)
+	for attempt := 1; ; attempt++ {
+		resp, err := doCompressed(r.http, req, payload, r.compressionThreshold)
+		if attempt >= attempts || ctx.Err() != nil || !shouldRetry(resp, err) {
+			return resp, err
+		}
+
+		delay := r.retry.backoff(attempt, resp)
+		if resp != nil {
+			io.Copy(io.Discard, resp.Body)
+			resp.Body.Close()
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		case <-timer.C:
+		}
+
+		req = req.Clone(ctx)
+		if payload != nil {
+			setRequestBody(req, payload)
+		}
+	}
+}
+
+// requester returns the shared request settings of the client, authorizing
+// requests with Token
+func (c *AgentClient) requester() requester {
+	return requester{
+		http:     c.HTTP,
+		endpoint: c.endpoint,
+		setHeaders: func(req *http.Request) {
+			c.setHeaders(req)
+			if token := c.Token(); token != "" {
+				req.Header.Set("Authorization", "Bearer "+token)
+			}
+		},
+		decoder:              c.ResponseDecoder,
+		compressionThreshold: c.CompressionThreshold,
+		retry:                c.Retry,
+	}
+}
+
+// doJSON sends a JSON API request for the client; see requester.doJSON. The
+// request is aborted when the client is closed.
+func (c *AgentClient) doJSON(ctx context.Context, method, path string, body, out interface{}) error {
+	ctx, done, err := c.beginRequest(ctx)
+	if err != nil {
+		return err
+	}
+	defer done()
+
+	return c.requester().doJSON(ctx, method, path, body, out)
+}
+
+// requester returns the shared request settings of the client
+func (c *OrgClient) requester() requester {
+	return requester{
+		http:       c.HTTP,
+		endpoint:   c.endpoint,
+		setHeaders: c.setHeaders,
+		decoder:    c.ResponseDecoder,
+		retry:      c.Retry,
+	}
+}
+
+// doJSON sends a JSON API request for the client; see requester.doJSON
+func (c *OrgClient) doJSON(ctx context.Context, method, path string, body, out interface{}) error {
+	return c.requester().doJSON(ctx, method, path, body, out)
+}
@@ -0,0 +1,129 @@
+package atoa
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestDoJSON_Retry(t *testing.T) {
+	tests := []struct {
+		name         string
+		method       string
+		statuses     []int
+		wantRequests int32
+		wantErr      bool
+	}{
+		{name: "GET retried until success", method: http.MethodGet, statuses: []int{503, 502, 200}, wantRequests: 3},
+		{name: "GET gives up after max attempts", method: http.MethodGet, statuses: []int{503, 503, 503, 200}, wantRequests: 3, wantErr: true},
+		{name: "PUT retried on 429", method: http.MethodPut, statuses: []int{429, 200}, wantRequests: 2},
+		{name: "POST not retried", method: http.MethodPost, statuses: []int{503, 200}, wantRequests: 1, wantErr: true},
+		{name: "client errors not retried", method: http.MethodGet, statuses: []int{400, 200}, wantRequests: 1, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var requests int32
+			ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				n := atomic.AddInt32(&requests, 1)
+				if body, _ := io.ReadAll(r.Body); r.Method != http.MethodGet && string(body) != `{"id":"x"}` {
+					t.Errorf("attempt %d body = %q, want the original body", n, body)
+				}
+				w.WriteHeader(tt.statuses[n-1])
+				w.Write([]byte(`{}`))
+			}))
+			defer ts.Close()
+
+			client := NewAgentClient(ts.URL, WithRetry(RetryPolicy{MaxAttempts: 3, MinBackoff: time.Millisecond}))
+			var body interface{}
+			if tt.method != http.MethodGet {
+				body = map[string]string{"id": "x"}
+			}
+			err := client.doJSON(context.Background(), tt.method, "/things", body, nil)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("doJSON() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got := atomic.LoadInt32(&requests); got != tt.wantRequests {
+				t.Errorf("server received %d requests, want %d", got, tt.wantRequests)
+			}
+		})
+	}
+}
+
+func TestDoJSON_NoRetryByDefault(t *testing.T) {
+	var requests int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer ts.Close()
+
+	client := NewAgentClient(ts.URL)
+	if err := client.doJSON(context.Background(), http.MethodGet, "/things", nil, nil); !hasStatus(err, http.StatusServiceUnavailable) {
+		t.Errorf("doJSON() error = %v, want 503 *APIError", err)
+	}
+	if got := atomic.LoadInt32(&requests); got != 1 {
+		t.Errorf("server received %d requests, want 1", got)
+	}
+}
+
+func TestDoJSON_RetryCanceledDuringBackoff(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Retry-After", "60")
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer ts.Close()
+
+	client := NewAgentClient(ts.URL, WithRetry(RetryPolicy{MaxAttempts: 3, MinBackoff: time.Millisecond}))
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	err := client.doJSON(ctx, http.MethodGet, "/things", nil, nil)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("doJSON() error = %v, want %v", err, context.DeadlineExceeded)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("doJSON() took %v, want it to stop at the deadline", elapsed)
+	}
+}
+
+func TestRetryPolicy_Backoff(t *testing.T) {
+	p := &RetryPolicy{MaxAttempts: 5, MinBackoff: 100 * time.Millisecond, MaxBackoff: 300 * time.Millisecond}
+
+	for retry, want := range map[int]time.Duration{1: 100 * time.Millisecond, 2: 200 * time.Millisecond, 3: 300 * time.Millisecond, 4: 300 * time.Millisecond} {
+		if got := p.backoff(retry, nil); got != want {
+			t.Errorf("backoff(%d) = %v, want %v", retry, got, want)
+		}
+	}
+
+	resp := &http.Response{Header: http.Header{"Retry-After": []string{"0"}}}
+	if got := p.backoff(3, resp); got != 0 {
+		t.Errorf("backoff() with Retry-After: 0 = %v, want 0", got)
+	}
+}
+
+func TestOrgClient_Retry(t *testing.T) {
+	var requests int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer ts.Close()
+
+	// Token requests are POSTs and so are never retried
+	client := NewOrgClient(ts.URL, WithRetry(RetryPolicy{MaxAttempts: 3, MinBackoff: time.Millisecond}))
+	_, err := client.RequestToken("test-org", "challenge", "signature")
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) {
+		t.Errorf("RequestToken() error = %v, want *APIError", err)
+	}
+	if got := atomic.LoadInt32(&requests); got != 1 {
+		t.Errorf("server received %d requests, want 1", got)
+	}
+}
@@ -0,0 +1,46 @@
+package atoa
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+)
+
+// HeaderRequestID carries the correlation ID of a request across agent hops
+const HeaderRequestID = "X-Request-ID"
+
+// requestIDKey is the context key for the request ID
+type requestIDKey struct{}
+
+// WithRequestID returns a context carrying id, which every client request
+// made with it sends as X-Request-ID. Services pass on the ID of the request
+// they are handling this way.
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, id)
+}
+
+// RequestIDFromContext returns the request ID stored by WithRequestID, or ""
+func RequestIDFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+// NewRequestID returns a random 128-bit request ID in hex
+func NewRequestID() string {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		// crypto/rand does not fail on supported platforms
+		panic(err)
+	}
+	return hex.EncodeToString(b[:])
+}
+
+// setRequestID sends the request ID from the request's context, or a new one
+func setRequestID(req *http.Request) {
+	id := RequestIDFromContext(req.Context())
+	if id == "" {
+		id = NewRequestID()
+	}
+	req.Header.Set(HeaderRequestID, id)
+}
@@ -0,0 +1,50 @@
+package atoa
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRequestID(t *testing.T) {
+	var got []string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = append(got, r.Header.Get(HeaderRequestID))
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`[]`))
+	}))
+	defer ts.Close()
+
+	client := NewAgentClient(ts.URL)
+	client.SetToken("valid-token")
+
+	// An ID in the context is passed through
+	ctx := WithRequestID(context.Background(), "req-123")
+	if RequestIDFromContext(ctx) != "req-123" {
+		t.Errorf("RequestIDFromContext() = %q, want %q", RequestIDFromContext(ctx), "req-123")
+	}
+	if _, err := client.ListOffers(ctx); err != nil {
+		t.Fatalf("ListOffers() error = %v", err)
+	}
+	if err := NewOrgClient(ts.URL).Ping(ctx); err != nil {
+		t.Fatalf("Ping() error = %v", err)
+	}
+
+	// Without one, each request gets a fresh ID
+	for i := 0; i < 2; i++ {
+		if _, err := client.ListOffers(context.Background()); err != nil {
+			t.Fatalf("ListOffers() error = %v", err)
+		}
+	}
+
+	if got[0] != "req-123" || got[1] != "req-123" {
+		t.Errorf("request IDs = %q, want req-123 passed through", got[:2])
+	}
+	if len(got[2]) != 32 || got[2] == got[3] {
+		t.Errorf("generated request IDs = %q, want two distinct 32-character IDs", got[2:])
+	}
+	if RequestIDFromContext(context.Background()) != "" {
+		t.Error("RequestIDFromContext() of empty context is not empty")
+	}
+}
@@ -0,0 +1,153 @@
+package atoa
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"strconv"
+	"strings"
+)
+
+// ProtocolVersion is the version of the Atoa protocol implemented by this
+// package. Offers set OfferRequirements.MinVersion against it.
+const ProtocolVersion = "1.0"
+
+// ErrRequirementsNotMet is returned by the client-side requirement pre-check
+// when the agent cannot use an offer
+var ErrRequirementsNotMet = errors.New("offer requirements not met")
+
+// ErrNoCommonCapabilities is returned by NegotiateSession when the two agents
+// share no capability
+var ErrNoCommonCapabilities = errors.New("no common capabilities")
+
+// MatchOption changes how required capabilities are matched
+type MatchOption func(*matchOptions)
+
+// matchOptions holds the settings collected from MatchOptions
+type matchOptions struct {
+	wildcards bool
+}
+
+// WithWildcards treats required capabilities as glob patterns, so that
+// "text.*" is met by "text.summarize". Patterns use path.Match syntax;
+// malformed patterns match nothing. Without it, matching is exact.
+func WithWildcards() MatchOption {
+	return func(o *matchOptions) {
+		o.wildcards = true
+	}
+}
+
+// MatchCapabilities reports whether capabilities include every required one
+func MatchCapabilities(capabilities, required []string, opts ...MatchOption) bool {
+	return len(missingCapabilities(capabilities, required, opts)) == 0
+}
+
+// NegotiateCapabilities returns the capabilities present in both local and
+// remote, in the order they appear in local and without duplicates
+func NegotiateCapabilities(local, remote []string) []string {
+	offered := make(map[string]bool, len(remote))
+	for _, capability := range remote {
+		offered[capability] = true
+	}
+
+	common := []string{}
+	for _, capability := range local {
+		if offered[capability] {
+			common = append(common, capability)
+			// Report each capability once even if local repeats it
+			delete(offered, capability)
+		}
+	}
+	return common
+}
+
+// missingCapabilities returns the required capabilities that are not present
+func missingCapabilities(capabilities, required []string, opts []MatchOption) []string {
+	var o matchOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	have := make(map[string]bool, len(capabilities))
+	for _, capability := range capabilities {
+		have[capability] = true
+	}
+
+	var missing []string
+	for _, capability := range required {
+		if have[capability] {
+			continue
+		}
+		if o.wildcards && matchesPattern(capabilities, capability) {
+			continue
+		}
+		missing = append(missing, capability)
+	}
+	return missing
+}
+
+// matchesPattern reports whether any capability matches the glob pattern
+func matchesPattern(capabilities []string, pattern string) bool {
+	for _, capability := range capabilities {
+		if ok, err := path.Match(pattern, capability); err == nil && ok {
+			return true
+		}
+	}
+	return false
+}
+
+// Check verifies that an agent with the given capabilities, speaking
+// ProtocolVersion, satisfies the requirements. Capabilities are matched as
+// in MatchCapabilities.
+func (r OfferRequirements) Check(capabilities []string, opts ...MatchOption) error {
+	if missing := missingCapabilities(capabilities, r.Capabilities, opts); len(missing) > 0 {
+		return fmt.Errorf("%w: missing capabilities %s", ErrRequirementsNotMet, strings.Join(missing, ", "))
+	}
+
+	if r.MinVersion != "" {
+		cmp, err := compareVersions(ProtocolVersion, r.MinVersion)
+		if err != nil {
+			return fmt.Errorf("invalid min_version: %w", err)
+		}
+		if cmp < 0 {
+			return fmt.Errorf("%w: protocol version %s is below %s", ErrRequirementsNotMet, ProtocolVersion, r.MinVersion)
+		}
+	}
+
+	return nil
+}
+
+// compareVersions compares dotted numeric versions such as "1.2.3", treating
+// missing components as zero. It returns -1, 0 or 1.
+func compareVersions(a, b string) (int, error) {
+	as, bs := strings.Split(a, "."), strings.Split(b, ".")
+	for i := 0; i < len(as) || i < len(bs); i++ {
+		x, err := versionComponent(as, i)
+		if err != nil {
+			return 0, fmt.Errorf("version %q: %w", a, err)
+		}
+		y, err := versionComponent(bs, i)
+		if err != nil {
+			return 0, fmt.Errorf("version %q: %w", b, err)
+		}
+		switch {
+		case x < y:
+			return -1, nil
+		case x > y:
+			return 1, nil
+		}
+	}
+	return 0, nil
+}
+
+// versionComponent returns the i-th numeric component of a split version
+func versionComponent(parts []string, i int) (int, error) {
+	if i >= len(parts) {
+		return 0, nil
+	}
+	n, err := strconv.Atoi(strings.TrimPrefix(parts[i], "v"))
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid component %q", parts[i])
+	}
+	return n, nil
+}
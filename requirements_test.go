@@ -0,0 +1,232 @@
+package atoa
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func TestOfferRequirements_Check(t *testing.T) {
+	tests := []struct {
+		name         string
+		requirements OfferRequirements
+		capabilities []string
+		wantErr      error
+	}{
+		{
+			name:         "capabilities and version satisfied",
+			requirements: OfferRequirements{Capabilities: []string{"text"}, MinVersion: "1.0"},
+			capabilities: []string{"text", "form"},
+			wantErr:      nil,
+		},
+		{
+			name:         "no requirements",
+			requirements: OfferRequirements{},
+			capabilities: nil,
+			wantErr:      nil,
+		},
+		{
+			name:         "missing capability",
+			requirements: OfferRequirements{Capabilities: []string{"text", "image"}},
+			capabilities: []string{"text"},
+			wantErr:      ErrRequirementsNotMet,
+		},
+		{
+			name:         "version too low",
+			requirements: OfferRequirements{MinVersion: "2.1"},
+			capabilities: []string{"text"},
+			wantErr:      ErrRequirementsNotMet,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.requirements.Check(tt.capabilities)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("Check() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+
+	if err := (OfferRequirements{MinVersion: "one"}).Check(nil); err == nil {
+		t.Error("Check() with malformed min_version error = nil, want error")
+	}
+}
+
+func TestCompareVersions(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"1.0", "1.0", 0},
+		{"1", "1.0.0", 0},
+		{"1.2", "1.10", -1},
+		{"2.0", "1.9.9", 1},
+		{"v1.1", "1.0", 1},
+	}
+
+	for _, tt := range tests {
+		got, err := compareVersions(tt.a, tt.b)
+		if err != nil {
+			t.Errorf("compareVersions(%q, %q) error = %v", tt.a, tt.b, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("compareVersions(%q, %q) = %v, want %v", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestCreateSessionForOffer_CheckRequirements(t *testing.T) {
+	requests := 0
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(`{"session_id": "session-1", "offer_id": "offer-1", "status": "active"}`))
+	}))
+	defer ts.Close()
+
+	client := &AgentClient{
+		BaseURL:           ts.URL,
+		HTTP:              &http.Client{},
+		CheckRequirements: true,
+	}
+	client.SetToken(issueTestAgentToken(t, true))
+
+	// The test agent only has the "text" capability
+	offer := &Offer{
+		Header:       OfferHeader{ID: "offer-1"},
+		Requirements: OfferRequirements{Capabilities: []string{"image"}},
+	}
+	_, err := client.CreateSessionForOffer(context.Background(), offer)
+	if !errors.Is(err, ErrRequirementsNotMet) {
+		t.Errorf("CreateSessionForOffer() error = %v, want %v", err, ErrRequirementsNotMet)
+	}
+	if requests != 0 {
+		t.Errorf("server received %d requests, want 0", requests)
+	}
+
+	offer.Requirements.Capabilities = []string{"text"}
+	session, err := client.CreateSessionForOffer(context.Background(), offer)
+	if err != nil {
+		t.Fatalf("CreateSessionForOffer() error = %v", err)
+	}
+	if session.SessionID != "session-1" {
+		t.Errorf("SessionID = %v, want %v", session.SessionID, "session-1")
+	}
+}
+
+func TestMatchCapabilities(t *testing.T) {
+	capabilities := []string{"text.summarize", "file", "image.ocr"}
+
+	tests := []struct {
+		name      string
+		required  []string
+		wildcards bool
+		want      bool
+	}{
+		{name: "exact match", required: []string{"file"}, want: true},
+		{name: "exact match with wildcards on", required: []string{"file", "text.summarize"}, wildcards: true, want: true},
+		{name: "pattern is literal by default", required: []string{"text.*"}, want: false},
+		{name: "wildcard match", required: []string{"text.*"}, wildcards: true, want: true},
+		{name: "wildcard and exact", required: []string{"image.*", "file"}, wildcards: true, want: true},
+		{name: "wildcard no match", required: []string{"audio.*"}, wildcards: true, want: false},
+		{name: "single-character wildcard", required: []string{"fil?"}, wildcards: true, want: true},
+		{name: "malformed pattern", required: []string{"text.["}, wildcards: true, want: false},
+		{name: "exact no match", required: []string{"audio"}, want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var opts []MatchOption
+			if tt.wildcards {
+				opts = append(opts, WithWildcards())
+			}
+			if got := MatchCapabilities(capabilities, tt.required, opts...); got != tt.want {
+				t.Errorf("MatchCapabilities() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestOfferRequirements_CheckWildcards(t *testing.T) {
+	requirements := OfferRequirements{Capabilities: []string{"text.*", "audio.*"}}
+
+	err := requirements.Check([]string{"text.summarize"}, WithWildcards())
+	if !errors.Is(err, ErrRequirementsNotMet) {
+		t.Fatalf("Check() error = %v, want %v", err, ErrRequirementsNotMet)
+	}
+	// Only the unmet pattern is reported
+	if want := "offer requirements not met: missing capabilities audio.*"; err.Error() != want {
+		t.Errorf("Check() error = %q, want %q", err.Error(), want)
+	}
+
+	if err := requirements.Check([]string{"text.summarize", "audio.transcribe"}, WithWildcards()); err != nil {
+		t.Errorf("Check() error = %v, want nil", err)
+	}
+}
+
+func TestNegotiateCapabilities(t *testing.T) {
+	tests := []struct {
+		name   string
+		local  []string
+		remote []string
+		want   []string
+	}{
+		{name: "identical", local: []string{"text", "file"}, remote: []string{"text", "file"}, want: []string{"text", "file"}},
+		{name: "keeps local order", local: []string{"file", "data", "text"}, remote: []string{"text", "file"}, want: []string{"file", "text"}},
+		{name: "drops duplicates", local: []string{"text", "text"}, remote: []string{"text"}, want: []string{"text"}},
+		{name: "disjoint", local: []string{"text"}, remote: []string{"image"}, want: []string{}},
+		{name: "empty remote", local: []string{"text"}, remote: nil, want: []string{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := NegotiateCapabilities(tt.local, tt.remote)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("NegotiateCapabilities() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNegotiateSession(t *testing.T) {
+	requests := 0
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(`{"session_id": "session-1", "offer_id": "offer-1", "status": "active"}`))
+	}))
+	defer ts.Close()
+
+	client := &AgentClient{
+		BaseURL: ts.URL,
+		HTTP:    &http.Client{},
+	}
+	client.SetToken(issueTestAgentToken(t, true))
+
+	// The test agent only has the "text" capability
+	_, _, err := client.NegotiateSession(context.Background(), "offer-1", []string{"image"})
+	if !errors.Is(err, ErrNoCommonCapabilities) {
+		t.Errorf("NegotiateSession() error = %v, want %v", err, ErrNoCommonCapabilities)
+	}
+	if requests != 0 {
+		t.Errorf("server received %d requests, want 0", requests)
+	}
+
+	session, capabilities, err := client.NegotiateSession(context.Background(), "offer-1", []string{"image", "text"})
+	if err != nil {
+		t.Fatalf("NegotiateSession() error = %v", err)
+	}
+	if session.SessionID != "session-1" {
+		t.Errorf("SessionID = %v, want %v", session.SessionID, "session-1")
+	}
+	if !reflect.DeepEqual(capabilities, []string{"text"}) {
+		t.Errorf("capabilities = %v, want %v", capabilities, []string{"text"})
+	}
+}
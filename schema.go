@@ -0,0 +1,330 @@
+package atoa
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// PayloadValidator checks message payloads against a JSON Schema registered
+// per message type. It supports the commonly used subset of JSON Schema:
+// type, properties, required, additionalProperties (boolean), items, enum,
+// minLength, maxLength, minimum, maximum, minItems and maxItems, plus the
+// annotations $schema, $id, $comment, title, description, default and
+// examples. Register rejects schemas that use any other keyword, so that no
+// constraint is silently skipped.
+type PayloadValidator struct {
+	mu      sync.RWMutex
+	schemas map[MessageType]*jsonSchema
+}
+
+// NewPayloadValidator creates a PayloadValidator with no schemas registered
+func NewPayloadValidator() *PayloadValidator {
+	return &PayloadValidator{schemas: make(map[MessageType]*jsonSchema)}
+}
+
+// Register sets the JSON Schema that payloads of the given message type must
+// satisfy, replacing any previous schema for that type
+func (v *PayloadValidator) Register(msgType MessageType, schema []byte) error {
+	var s jsonSchema
+	if err := json.Unmarshal(schema, &s); err != nil {
+		return fmt.Errorf("invalid schema for message type %q: %w", msgType, err)
+	}
+	if err := checkSchemaKeywords(schema, "#"); err != nil {
+		return fmt.Errorf("invalid schema for message type %q: %w", msgType, err)
+	}
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.schemas[msgType] = &s
+	return nil
+}
+
+// Validate checks a payload against the schema registered for msgType.
+// Payloads of types without a schema are accepted.
+func (v *PayloadValidator) Validate(msgType MessageType, payload json.RawMessage) error {
+	v.mu.RLock()
+	schema := v.schemas[msgType]
+	v.mu.RUnlock()
+
+	if schema == nil {
+		return nil
+	}
+
+	var instance interface{}
+	if err := json.Unmarshal(payload, &instance); err != nil {
+		return fmt.Errorf("payload is not valid JSON: %w", err)
+	}
+
+	var errs []SchemaError
+	schema.validate(instance, "", "#", &errs)
+	if len(errs) > 0 {
+		return &PayloadValidationError{Type: msgType, Errors: errs}
+	}
+	return nil
+}
+
+// ValidatePayload checks the message payload against the schema registered
+// in v for the message type
+func (m *A2AMessage) ValidatePayload(v *PayloadValidator) error {
+	if v == nil {
+		return errors.New("payload validator is nil")
+	}
+	return v.Validate(m.Type, m.Payload)
+}
+
+// SchemaError describes a single schema violation
+type SchemaError struct {
+	// Path is the JSON Pointer to the offending value in the payload
+	Path string
+	// SchemaPath is the location of the failing keyword in the schema
+	SchemaPath string
+	Message    string
+}
+
+// Error implements the error interface
+func (e SchemaError) Error() string {
+	path := e.Path
+	if path == "" {
+		path = "/"
+	}
+	return fmt.Sprintf("%s: %s (at %s)", path, e.Message, e.SchemaPath)
+}
+
+// PayloadValidationError lists every schema violation found in a payload
+type PayloadValidationError struct {
+	Type   MessageType
+	Errors []SchemaError
+}
+
+// Error implements the error interface
+func (e *PayloadValidationError) Error() string {
+	msgs := make([]string, len(e.Errors))
+	for i, err := range e.Errors {
+		msgs[i] = err.Error()
+	}
+	return fmt.Sprintf("payload does not match schema for message type %q: %s", e.Type, strings.Join(msgs, "; "))
+}
+
+// jsonSchema is the supported subset of a JSON Schema document
+type jsonSchema struct {
+	Type                 schemaTypes            `json:"type"`
+	Properties           map[string]*jsonSchema `json:"properties"`
+	Required             []string               `json:"required"`
+	AdditionalProperties *bool                  `json:"additionalProperties"`
+	Items                *jsonSchema            `json:"items"`
+	Enum                 []interface{}          `json:"enum"`
+	MinLength            *int                   `json:"minLength"`
+	MaxLength            *int                   `json:"maxLength"`
+	Minimum              *float64               `json:"minimum"`
+	Maximum              *float64               `json:"maximum"`
+	MinItems             *int                   `json:"minItems"`
+	MaxItems             *int                   `json:"maxItems"`
+}
+
+// supportedSchemaKeywords are the keywords jsonSchema enforces, and
+// annotations that have no effect on validation
+var supportedSchemaKeywords = map[string]bool{
+	"type": true, "properties": true, "required": true, "additionalProperties": true,
+	"items": true, "enum": true, "minLength": true, "maxLength": true,
+	"minimum": true, "maximum": true, "minItems": true, "maxItems": true,
+
+	"$schema": true, "$id": true, "$comment": true, "title": true,
+	"description": true, "default": true, "examples": true,
+}
+
+// checkSchemaKeywords returns an error naming the first keyword in the schema
+// at schemaPath, or in its subschemas, that is not supported
+func checkSchemaKeywords(data json.RawMessage, schemaPath string) error {
+	var keywords map[string]json.RawMessage
+	if err := json.Unmarshal(data, &keywords); err != nil {
+		return fmt.Errorf("%s: schema must be an object", schemaPath)
+	}
+
+	names := make([]string, 0, len(keywords))
+	for name := range keywords {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if !supportedSchemaKeywords[name] {
+			return fmt.Errorf("%s: unsupported keyword %q", schemaPath, name)
+		}
+	}
+
+	if items, ok := keywords["items"]; ok {
+		if err := checkSchemaKeywords(items, schemaPath+"/items"); err != nil {
+			return err
+		}
+	}
+	if raw, ok := keywords["properties"]; ok {
+		var properties map[string]json.RawMessage
+		if err := json.Unmarshal(raw, &properties); err != nil {
+			return fmt.Errorf("%s/properties: must be an object", schemaPath)
+		}
+		names := make([]string, 0, len(properties))
+		for name := range properties {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			if err := checkSchemaKeywords(properties[name], schemaPath+"/properties/"+escapeJSONPointer(name)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// schemaTypes holds the "type" keyword, which may be a string or an array
+type schemaTypes []string
+
+// UnmarshalJSON accepts both forms of the "type" keyword
+func (t *schemaTypes) UnmarshalJSON(data []byte) error {
+	var single string
+	if err := json.Unmarshal(data, &single); err == nil {
+		*t = schemaTypes{single}
+		return nil
+	}
+
+	var multiple []string
+	if err := json.Unmarshal(data, &multiple); err != nil {
+		return errors.New("type must be a string or an array of strings")
+	}
+	*t = multiple
+	return nil
+}
+
+// validate appends every violation of s by instance to errs
+func (s *jsonSchema) validate(instance interface{}, path, schemaPath string, errs *[]SchemaError) {
+	fail := func(keyword, format string, args ...interface{}) {
+		*errs = append(*errs, SchemaError{
+			Path:       path,
+			SchemaPath: schemaPath + "/" + keyword,
+			Message:    fmt.Sprintf(format, args...),
+		})
+	}
+
+	if len(s.Type) > 0 && !s.Type.matches(instance) {
+		fail("type", "expected %s, got %s", strings.Join(s.Type, " or "), jsonTypeOf(instance))
+		return
+	}
+
+	if len(s.Enum) > 0 {
+		found := false
+		for _, allowed := range s.Enum {
+			if reflect.DeepEqual(allowed, instance) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			fail("enum", "value is not one of the allowed values")
+		}
+	}
+
+	switch value := instance.(type) {
+	case string:
+		length := len([]rune(value))
+		if s.MinLength != nil && length < *s.MinLength {
+			fail("minLength", "length %d is less than %d", length, *s.MinLength)
+		}
+		if s.MaxLength != nil && length > *s.MaxLength {
+			fail("maxLength", "length %d is greater than %d", length, *s.MaxLength)
+		}
+	case float64:
+		if s.Minimum != nil && value < *s.Minimum {
+			fail("minimum", "%v is less than %v", value, *s.Minimum)
+		}
+		if s.Maximum != nil && value > *s.Maximum {
+			fail("maximum", "%v is greater than %v", value, *s.Maximum)
+		}
+	case []interface{}:
+		if s.MinItems != nil && len(value) < *s.MinItems {
+			fail("minItems", "%d items is less than %d", len(value), *s.MinItems)
+		}
+		if s.MaxItems != nil && len(value) > *s.MaxItems {
+			fail("maxItems", "%d items is greater than %d", len(value), *s.MaxItems)
+		}
+		if s.Items != nil {
+			for i, item := range value {
+				s.Items.validate(item, path+"/"+strconv.Itoa(i), schemaPath+"/items", errs)
+			}
+		}
+	case map[string]interface{}:
+		for _, name := range s.Required {
+			if _, ok := value[name]; !ok {
+				fail("required", "missing required property %q", name)
+			}
+		}
+
+		// Iterate in a stable order so errors are reported deterministically
+		names := make([]string, 0, len(value))
+		for name := range value {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		for _, name := range names {
+			propertyPath := path + "/" + escapeJSONPointer(name)
+			if property, ok := s.Properties[name]; ok {
+				property.validate(value[name], propertyPath, schemaPath+"/properties/"+escapeJSONPointer(name), errs)
+			} else if s.AdditionalProperties != nil && !*s.AdditionalProperties {
+				*errs = append(*errs, SchemaError{
+					Path:       propertyPath,
+					SchemaPath: schemaPath + "/additionalProperties",
+					Message:    "additional property is not allowed",
+				})
+			}
+		}
+	}
+}
+
+// matches reports whether instance has one of the types
+func (t schemaTypes) matches(instance interface{}) bool {
+	actual := jsonTypeOf(instance)
+	for _, want := range t {
+		if want == actual {
+			return true
+		}
+		// Every integer is also a number
+		if want == "number" && actual == "integer" {
+			return true
+		}
+	}
+	return false
+}
+
+// jsonTypeOf returns the JSON Schema type name of a decoded JSON value
+func jsonTypeOf(instance interface{}) string {
+	switch value := instance.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case string:
+		return "string"
+	case float64:
+		if value == math.Trunc(value) {
+			return "integer"
+		}
+		return "number"
+	case []interface{}:
+		return "array"
+	case map[string]interface{}:
+		return "object"
+	default:
+		return fmt.Sprintf("%T", instance)
+	}
+}
+
+// escapeJSONPointer escapes a property name for use in a JSON Pointer
+func escapeJSONPointer(name string) string {
+	return strings.ReplaceAll(strings.ReplaceAll(name, "~", "~0"), "/", "~1")
+}
@@ -0,0 +1,209 @@
+package atoa
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testTextSchema = `{
+	"type": "object",
+	"required": ["content"],
+	"additionalProperties": false,
+	"properties": {
+		"content": {"type": "string", "minLength": 1, "maxLength": 20},
+		"lang": {"enum": ["en", "de"]},
+		"tags": {"type": "array", "maxItems": 2, "items": {"type": "string"}},
+		"priority": {"type": "integer", "minimum": 0, "maximum": 5}
+	}
+}`
+
+func TestPayloadValidator_Validate(t *testing.T) {
+	v := NewPayloadValidator()
+	if err := v.Register(MessageTypeText, []byte(testTextSchema)); err != nil {
+		t.Fatalf("Register() error = %v", err)
+	}
+
+	tests := []struct {
+		name        string
+		msgType     MessageType
+		payload     string
+		wantErr     bool
+		wantPaths   []string
+		wantSchemas []string
+	}{
+		{
+			name:    "valid payload",
+			msgType: MessageTypeText,
+			payload: `{"content": "Hello", "lang": "en", "tags": ["a"], "priority": 3}`,
+			wantErr: false,
+		},
+		{
+			name:    "type without schema",
+			msgType: MessageTypeData,
+			payload: `{"anything": true}`,
+			wantErr: false,
+		},
+		{
+			name:        "missing required property",
+			msgType:     MessageTypeText,
+			payload:     `{}`,
+			wantErr:     true,
+			wantPaths:   []string{""},
+			wantSchemas: []string{"#/required"},
+		},
+		{
+			name:        "wrong property type",
+			msgType:     MessageTypeText,
+			payload:     `{"content": 42}`,
+			wantErr:     true,
+			wantPaths:   []string{"/content"},
+			wantSchemas: []string{"#/properties/content/type"},
+		},
+		{
+			name:        "several violations",
+			msgType:     MessageTypeText,
+			payload:     `{"content": "", "lang": "fr", "tags": ["a", 1, "c"], "priority": 1.5, "extra": 1}`,
+			wantErr:     true,
+			wantPaths:   []string{"/content", "/extra", "/lang", "/priority", "/tags", "/tags/1"},
+			wantSchemas: []string{"#/properties/content/minLength", "#/additionalProperties", "#/properties/lang/enum", "#/properties/priority/type", "#/properties/tags/maxItems", "#/properties/tags/items/type"},
+		},
+		{
+			name:    "invalid JSON",
+			msgType: MessageTypeText,
+			payload: `{`,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := v.Validate(tt.msgType, json.RawMessage(tt.payload))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantPaths == nil {
+				return
+			}
+
+			var validationErr *PayloadValidationError
+			if !errors.As(err, &validationErr) {
+				t.Fatalf("Validate() error = %T, want *PayloadValidationError", err)
+			}
+			if len(validationErr.Errors) != len(tt.wantPaths) {
+				t.Fatalf("Validate() errors = %v, want %d errors", validationErr.Errors, len(tt.wantPaths))
+			}
+			for i, schemaErr := range validationErr.Errors {
+				if schemaErr.Path != tt.wantPaths[i] {
+					t.Errorf("Errors[%d].Path = %q, want %q", i, schemaErr.Path, tt.wantPaths[i])
+				}
+				if schemaErr.SchemaPath != tt.wantSchemas[i] {
+					t.Errorf("Errors[%d].SchemaPath = %q, want %q", i, schemaErr.SchemaPath, tt.wantSchemas[i])
+				}
+			}
+		})
+	}
+}
+
+func TestPayloadValidator_RegisterInvalidSchema(t *testing.T) {
+	v := NewPayloadValidator()
+	if err := v.Register(MessageTypeText, []byte(`{"type": 42}`)); err == nil {
+		t.Error("Register() with invalid type keyword error = nil, want error")
+	}
+	if err := v.Register(MessageTypeText, []byte(`not json`)); err == nil {
+		t.Error("Register() with invalid JSON error = nil, want error")
+	}
+}
+
+func TestPayloadValidator_RegisterUnsupportedKeyword(t *testing.T) {
+	tests := []struct {
+		name    string
+		schema  string
+		wantErr string
+	}{
+		{
+			name:    "top level",
+			schema:  `{"type": "object", "oneOf": [{"required": ["content"]}]}`,
+			wantErr: `#: unsupported keyword "oneOf"`,
+		},
+		{
+			name:    "property",
+			schema:  `{"type": "object", "properties": {"content": {"type": "string", "pattern": "^[0-9]+$"}}}`,
+			wantErr: `#/properties/content: unsupported keyword "pattern"`,
+		},
+		{
+			name:    "items",
+			schema:  `{"type": "array", "items": {"type": "string", "format": "email"}}`,
+			wantErr: `#/items: unsupported keyword "format"`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := NewPayloadValidator()
+			err := v.Register(MessageTypeText, []byte(tt.schema))
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("Register() error = %v, want %q", err, tt.wantErr)
+			}
+			// Nothing was registered, so nothing is validated
+			if err := v.Validate(MessageTypeText, json.RawMessage(`{"content": "123 NOT"}`)); err != nil {
+				t.Errorf("Validate() error = %v, want nil", err)
+			}
+		})
+	}
+
+	// Annotations are accepted
+	v := NewPayloadValidator()
+	schema := `{"$schema": "https://json-schema.org/draft/2020-12/schema", "title": "Text", "description": "A text message", "type": "object", "properties": {"content": {"type": "string", "default": "", "examples": ["hi"]}}}`
+	if err := v.Register(MessageTypeText, []byte(schema)); err != nil {
+		t.Errorf("Register() with annotations error = %v", err)
+	}
+}
+
+func TestSendMessage_PayloadValidator(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	v := NewPayloadValidator()
+	if err := v.Register(MessageTypeText, []byte(testTextSchema)); err != nil {
+		t.Fatalf("Register() error = %v", err)
+	}
+
+	client := &AgentClient{
+		BaseURL:          server.URL,
+		HTTP:             &http.Client{},
+		PayloadValidator: v,
+	}
+	client.SetToken("valid-token")
+	msg := A2AMessage{
+		SessionID:   "session-123",
+		FromAgentID: "agent-1",
+		ToAgentID:   "agent-2",
+		Type:        MessageTypeText,
+		Payload:     json.RawMessage(`{"text": "Hello"}`),
+		Timestamp:   time.Now(),
+	}
+
+	err := client.SendMessage(context.Background(), msg)
+	var validationErr *PayloadValidationError
+	if !errors.As(err, &validationErr) {
+		t.Errorf("SendMessage() error = %v, want *PayloadValidationError", err)
+	}
+	if requests != 0 {
+		t.Errorf("server received %d requests, want 0", requests)
+	}
+
+	msg.Payload = json.RawMessage(`{"content": "Hello"}`)
+	if err := client.SendMessage(context.Background(), msg); err != nil {
+		t.Errorf("SendMessage() with valid payload error = %v", err)
+	}
+}
@@ -0,0 +1,67 @@
+package atoa
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"strings"
+)
+
+// maxSSELineBytes bounds a single line of a server-sent event stream
+const maxSSELineBytes = 1 << 20
+
+// sseEvent is one event read from a text/event-stream body
+type sseEvent struct {
+	ID    string
+	Event string
+	Data  []byte
+}
+
+// readSSE parses a text/event-stream body and calls handle for each event
+// until the body ends, a read fails or handle returns false. Comments and
+// unknown fields are ignored; events without data are not dispatched.
+func readSSE(r io.Reader, handle func(sseEvent) bool) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 4096), maxSSELineBytes)
+
+	var event sseEvent
+	var data bytes.Buffer
+	hasData := false
+
+	for scanner.Scan() {
+		line := scanner.Text()
+
+		// A blank line dispatches the event collected so far
+		if line == "" {
+			if hasData {
+				event.Data = bytes.Clone(data.Bytes())
+				if !handle(event) {
+					return nil
+				}
+			}
+			event = sseEvent{ID: event.ID}
+			data.Reset()
+			hasData = false
+			continue
+		}
+		if strings.HasPrefix(line, ":") {
+			continue
+		}
+
+		field, value, _ := strings.Cut(line, ":")
+		value = strings.TrimPrefix(value, " ")
+		switch field {
+		case "data":
+			if hasData {
+				data.WriteByte('\n')
+			}
+			data.WriteString(value)
+			hasData = true
+		case "event":
+			event.Event = value
+		case "id":
+			event.ID = value
+		}
+	}
+	return scanner.Err()
+}
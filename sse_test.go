@@ -0,0 +1,71 @@
+package atoa
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestReadSSE(t *testing.T) {
+	tests := []struct {
+		name   string
+		stream string
+		want   []sseEvent
+	}{
+		{
+			name:   "single event",
+			stream: "event: added\ndata: {}\n\n",
+			want:   []sseEvent{{Event: "added", Data: []byte("{}")}},
+		},
+		{
+			name:   "multi-line data",
+			stream: "data: first\ndata:second\n\n",
+			want:   []sseEvent{{Data: []byte("first\nsecond")}},
+		},
+		{
+			name:   "id carries over to later events",
+			stream: "id: 7\ndata: a\n\nevent: x\ndata: b\n\n",
+			want:   []sseEvent{{ID: "7", Data: []byte("a")}, {ID: "7", Event: "x", Data: []byte("b")}},
+		},
+		{
+			name:   "comments and events without data are skipped",
+			stream: ": keep-alive\n\nevent: empty\n\nretry: 10\ndata: c\n\n",
+			want:   []sseEvent{{Data: []byte("c")}},
+		},
+		{
+			name:   "unterminated event is dropped",
+			stream: "data: partial\n",
+			want:   nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []sseEvent
+			err := readSSE(strings.NewReader(tt.stream), func(e sseEvent) bool {
+				got = append(got, e)
+				return true
+			})
+			if err != nil {
+				t.Fatalf("readSSE() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("readSSE() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReadSSE_StopsWhenHandlerReturnsFalse(t *testing.T) {
+	calls := 0
+	err := readSSE(strings.NewReader("data: a\n\ndata: b\n\n"), func(sseEvent) bool {
+		calls++
+		return false
+	})
+	if err != nil {
+		t.Fatalf("readSSE() error = %v", err)
+	}
+	if calls != 1 {
+		t.Errorf("handler called %d times, want 1", calls)
+	}
+}
@@ -0,0 +1,439 @@
+// Package testserver provides an in-memory fake of the Atoa platform API for
+// tests that exercise the atoa clients end-to-end.
+package testserver
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"time"
+
+	atoa "github.com/unscalers/atoamarket_poc/atoa_go"
+)
+
+// FakePlatform is an in-memory implementation of the platform endpoints used
+// by atoa.OrgClient and atoa.AgentClient. Tokens are issued and verified with
+// the real atoa token functions using the platform's signing key.
+type FakePlatform struct {
+	server     *httptest.Server
+	privateKey *ecdsa.PrivateKey
+
+	mu         sync.Mutex
+	orgs       map[string]*atoa.OrgCard
+	challenges map[string]atoa.Challenge
+	nonces     *atoa.MemoryNonceStore
+	unverified map[string]bool
+	offers     []atoa.Offer
+	sessions   map[string]*atoa.Session
+	messages   []atoa.A2AMessage
+	nextID     int
+}
+
+// NewFakePlatform starts a fake platform listening on a local address. Call
+// Close when done.
+func NewFakePlatform() *FakePlatform {
+	privateKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		panic("testserver: failed to generate signing key: " + err.Error())
+	}
+
+	p := &FakePlatform{
+		privateKey: privateKey,
+		orgs:       make(map[string]*atoa.OrgCard),
+		challenges: make(map[string]atoa.Challenge),
+		nonces:     atoa.NewMemoryNonceStore(),
+		unverified: make(map[string]bool),
+		sessions:   make(map[string]*atoa.Session),
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/orgs/register", p.handleRegisterOrg)
+	mux.HandleFunc("/orgs/token", p.handleOrgToken)
+	mux.HandleFunc("/agents/token", p.handleAgentToken)
+	mux.HandleFunc("/offers", p.handleOffers)
+	mux.HandleFunc("/offers/", p.handleOffer)
+	mux.HandleFunc("/sessions", p.handleSessions)
+	mux.HandleFunc("/messages", p.handleMessages)
+	p.server = httptest.NewServer(mux)
+
+	return p
+}
+
+// URL returns the base URL to pass to atoa.NewOrgClient or atoa.NewAgentClient
+func (p *FakePlatform) URL() string {
+	return p.server.URL
+}
+
+// PrivateKey returns the key the platform signs tokens with
+func (p *FakePlatform) PrivateKey() *ecdsa.PrivateKey {
+	return p.privateKey
+}
+
+// PublicKey returns the key that verifies tokens issued by the platform
+func (p *FakePlatform) PublicKey() *ecdsa.PublicKey {
+	return &p.privateKey.PublicKey
+}
+
+// Close shuts down the platform server
+func (p *FakePlatform) Close() {
+	p.server.Close()
+}
+
+// SetOrgVerified sets the verified status put into tokens issued for the org.
+// Orgs are verified by default.
+func (p *FakePlatform) SetOrgVerified(orgID string, verified bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.unverified[orgID] = !verified
+}
+
+// AddOffer publishes an offer to be returned by /offers
+func (p *FakePlatform) AddOffer(offer atoa.Offer) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.offers = append(p.offers, offer)
+}
+
+// Sessions returns a copy of all sessions created so far
+func (p *FakePlatform) Sessions() []atoa.Session {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	sessions := make([]atoa.Session, 0, len(p.sessions))
+	for _, s := range p.sessions {
+		sessions = append(sessions, *s)
+	}
+	return sessions
+}
+
+// Messages returns a copy of all messages received so far
+func (p *FakePlatform) Messages() []atoa.A2AMessage {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([]atoa.A2AMessage(nil), p.messages...)
+}
+
+func (p *FakePlatform) handleRegisterOrg(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	var card atoa.OrgCard
+	if err := json.NewDecoder(r.Body).Decode(&card); err != nil {
+		http.Error(w, "invalid org card", http.StatusBadRequest)
+		return
+	}
+	if err := card.Validate(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	ch, err := atoa.NewChallenge(5 * time.Minute)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	// OrgCard.Validate accepts EC and RSA keys only
+	ch.Algorithm = "ES256"
+	if _, err := atoa.ParsePublicKeyPEM(card.PublicKey); err != nil {
+		ch.Algorithm = "RS256"
+	}
+
+	p.mu.Lock()
+	p.orgs[card.OrgID] = &card
+	p.challenges[card.OrgID] = ch
+	p.mu.Unlock()
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"challenge":  ch.String(),
+		"nonce":      ch.Nonce,
+		"issued_at":  ch.IssuedAt,
+		"expires_at": ch.ExpiresAt,
+		"algorithm":  ch.Algorithm,
+	})
+}
+
+func (p *FakePlatform) handleOrgToken(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req struct {
+		OrgID     string `json:"org_id"`
+		Challenge string `json:"challenge"`
+		Signature string `json:"signature"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid request", http.StatusBadRequest)
+		return
+	}
+
+	p.mu.Lock()
+	card, ok := p.orgs[req.OrgID]
+	challenge, pending := p.challenges[req.OrgID]
+	verified := !p.unverified[req.OrgID]
+	p.mu.Unlock()
+
+	if !ok || !pending || challenge.String() != req.Challenge {
+		http.Error(w, "unknown org or challenge", http.StatusUnauthorized)
+		return
+	}
+	valid, err := atoa.VerifyChallengeSignatureOnce(p.nonces, challenge, req.Signature, card.PublicKey)
+	if err != nil || !valid {
+		http.Error(w, "invalid signature", http.StatusUnauthorized)
+		return
+	}
+
+	// The answered challenge is not needed any more
+	p.mu.Lock()
+	delete(p.challenges, req.OrgID)
+	p.mu.Unlock()
+
+	token, err := atoa.IssueOrgToken(req.OrgID, verified, p.privateKey)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]string{"token": token})
+}
+
+func (p *FakePlatform) handleAgentToken(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req struct {
+		AgentCard *atoa.AgentCard `json:"agent_card"`
+		OrgToken  string          `json:"org_token"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.AgentCard == nil {
+		http.Error(w, "invalid request", http.StatusBadRequest)
+		return
+	}
+
+	token, err := atoa.IssueAgentToken(req.AgentCard, req.OrgToken, p.privateKey)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]string{"token": token})
+}
+
+func (p *FakePlatform) handleOffers(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	if _, ok := p.authenticate(w, r); !ok {
+		return
+	}
+
+	p.mu.Lock()
+	offers := append([]atoa.Offer{}, p.offers...)
+	p.mu.Unlock()
+
+	writeJSON(w, http.StatusOK, offers)
+}
+
+func (p *FakePlatform) handleOffer(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodPut && r.Method != http.MethodDelete {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	if _, ok := p.authenticate(w, r); !ok {
+		return
+	}
+
+	offerID := strings.TrimPrefix(r.URL.Path, "/offers/")
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for i, offer := range p.offers {
+		if offer.Header.ID != offerID {
+			continue
+		}
+		switch r.Method {
+		case http.MethodDelete:
+			p.offers = append(p.offers[:i], p.offers[i+1:]...)
+			w.WriteHeader(http.StatusNoContent)
+		case http.MethodPut:
+			var updated atoa.Offer
+			if err := json.NewDecoder(r.Body).Decode(&updated); err != nil || updated.Header.ID != offerID {
+				http.Error(w, "invalid offer", http.StatusBadRequest)
+				return
+			}
+			p.offers[i] = updated
+			writeJSON(w, http.StatusOK, updated)
+		default:
+			writeJSON(w, http.StatusOK, offer)
+		}
+		return
+	}
+	http.Error(w, "offer not found", http.StatusNotFound)
+}
+
+func (p *FakePlatform) handleSessions(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		p.handleListSessions(w, r)
+	case http.MethodPost:
+		p.handleCreateSession(w, r)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+func (p *FakePlatform) handleListSessions(w http.ResponseWriter, r *http.Request) {
+	claims, ok := p.authenticate(w, r)
+	if !ok {
+		return
+	}
+
+	status := r.URL.Query().Get("status")
+	offerID := r.URL.Query().Get("offer_id")
+
+	p.mu.Lock()
+	sessions := []atoa.Session{}
+	for _, s := range p.sessions {
+		if s.FromAgentID != claims.AgentID && s.ToAgentID != claims.AgentID {
+			continue
+		}
+		if (status != "" && s.Status != status) || (offerID != "" && s.OfferID != offerID) {
+			continue
+		}
+		sessions = append(sessions, *s)
+	}
+	p.mu.Unlock()
+
+	writeJSON(w, http.StatusOK, sessions)
+}
+
+func (p *FakePlatform) handleCreateSession(w http.ResponseWriter, r *http.Request) {
+	claims, ok := p.authenticate(w, r)
+	if !ok {
+		return
+	}
+
+	var req struct {
+		OfferID string `json:"offer_id"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid request", http.StatusBadRequest)
+		return
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if !p.hasOffer(req.OfferID) {
+		http.Error(w, "unknown offer", http.StatusBadRequest)
+		return
+	}
+
+	p.nextID++
+	now := time.Now().UTC()
+	session := &atoa.Session{
+		SessionID:   fmt.Sprintf("session-%d", p.nextID),
+		OfferID:     req.OfferID,
+		FromAgentID: claims.AgentID,
+		CreatedAt:   now.Format(time.RFC3339),
+		ExpiresAt:   now.Add(atoa.DefaultTokenExpiry).Format(time.RFC3339),
+		Status:      atoa.SessionStatusActive,
+	}
+	p.sessions[session.SessionID] = session
+
+	writeJSON(w, http.StatusCreated, session)
+}
+
+func (p *FakePlatform) handleMessages(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	claims, ok := p.authenticate(w, r)
+	if !ok {
+		return
+	}
+
+	var msg atoa.A2AMessage
+	if err := json.NewDecoder(r.Body).Decode(&msg); err != nil {
+		http.Error(w, "invalid message format", http.StatusBadRequest)
+		return
+	}
+	// Custom types are relayed like built-in ones, as from clients with
+	// AllowCustomMessageTypes set
+	if err := msg.ValidateAllowingCustom(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if msg.FromAgentID != claims.AgentID {
+		http.Error(w, "from_agent_id does not match token", http.StatusForbidden)
+		return
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if _, ok := p.sessions[msg.SessionID]; !ok {
+		http.Error(w, "unknown session", http.StatusNotFound)
+		return
+	}
+	p.messages = append(p.messages, msg)
+
+	writeJSON(w, http.StatusOK, atoa.MessageReceipt{
+		MessageID:  fmt.Sprintf("message-%d", len(p.messages)),
+		AcceptedAt: time.Now().UTC(),
+		Status:     "accepted",
+	})
+}
+
+// authenticate verifies the bearer agent token and requires it to be
+// verified, writing the error response itself when it is not
+func (p *FakePlatform) authenticate(w http.ResponseWriter, r *http.Request) (*atoa.AgentTokenClaims, bool) {
+	token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if token == "" {
+		http.Error(w, "missing token", http.StatusUnauthorized)
+		return nil, false
+	}
+
+	// Requiring the agent audience keeps org tokens out of agent endpoints
+	opts := atoa.DefaultVerifyOptions()
+	opts.Audiences = []string{atoa.AgentTokenAudience}
+	claims := &atoa.AgentTokenClaims{}
+	if err := atoa.ParseTokenWithOptions(token, p.PublicKey(), claims, opts); err != nil {
+		http.Error(w, "invalid token", http.StatusUnauthorized)
+		return nil, false
+	}
+	if !claims.Verified {
+		http.Error(w, "agent is not verified", http.StatusForbidden)
+		return nil, false
+	}
+
+	return claims, true
+}
+
+// hasOffer reports whether an offer with the given ID exists. p.mu must be held.
+func (p *FakePlatform) hasOffer(offerID string) bool {
+	for _, offer := range p.offers {
+		if offer.Header.ID == offerID {
+			return true
+		}
+	}
+	return false
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,180 @@
+package testserver
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	atoa "github.com/unscalers/atoamarket_poc/atoa_go"
+)
+
+func TestFakePlatform_EndToEnd(t *testing.T) {
+	platform := NewFakePlatform()
+	defer platform.Close()
+
+	platform.AddOffer(atoa.Offer{
+		Header: atoa.OfferHeader{ID: "offer-1", Title: "Test Offer", Type: "service"},
+	})
+
+	orgKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate org key: %v", err)
+	}
+	publicKeyPEM, err := atoa.MarshalPublicKeyPEM(&orgKey.PublicKey)
+	if err != nil {
+		t.Fatalf("failed to marshal public key: %v", err)
+	}
+
+	// Register the org and exchange the signed challenge for a token
+	orgClient := atoa.NewOrgClient(platform.URL())
+	challenge, err := orgClient.RegisterOrg(&atoa.OrgCard{
+		OrgID:     "test-org",
+		Name:      "Test Org",
+		Domain:    "test.org",
+		PublicKey: publicKeyPEM,
+	})
+	if err != nil {
+		t.Fatalf("RegisterOrg() error = %v", err)
+	}
+	if challenge.Algorithm != "ES256" {
+		t.Errorf("challenge.Algorithm = %v, want %v", challenge.Algorithm, "ES256")
+	}
+	signature, err := atoa.SignChallenge(challenge.String(), orgKey)
+	if err != nil {
+		t.Fatalf("SignChallenge() error = %v", err)
+	}
+	orgToken, err := orgClient.RequestTokenForChallenge("test-org", challenge, signature)
+	if err != nil {
+		t.Fatalf("RequestTokenForChallenge() error = %v", err)
+	}
+
+	// The challenge cannot be replayed
+	if _, err := orgClient.RequestTokenForChallenge("test-org", challenge, signature); err == nil {
+		t.Error("RequestTokenForChallenge() with replayed challenge error = nil, want error")
+	}
+
+	// The org token is not accepted on agent endpoints
+	orgAsAgent := atoa.NewAgentClient(platform.URL())
+	orgAsAgent.SetToken(orgToken)
+	orgAsAgent.SkipVerifiedCheck = true
+	var apiErr *atoa.APIError
+	if _, err := orgAsAgent.ListOffers(context.Background()); !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusUnauthorized {
+		t.Errorf("ListOffers() with org token error = %v, want 401", err)
+	}
+
+	// Register the agent and use its token against the agent endpoints
+	agentClient := atoa.NewAgentClient(platform.URL())
+	agentToken, err := agentClient.RegisterAgent(&atoa.AgentCard{
+		AgentID:      "agent-1",
+		OrgID:        "test-org",
+		Capabilities: []string{"text"},
+	}, orgToken)
+	if err != nil {
+		t.Fatalf("RegisterAgent() error = %v", err)
+	}
+	agentClient.SetToken(agentToken)
+
+	// Nor can the agent token stand in for the org token
+	if _, err := atoa.NewAgentClient(platform.URL()).RegisterAgent(&atoa.AgentCard{
+		AgentID:      "agent-2",
+		OrgID:        "test-org",
+		Capabilities: []string{"text"},
+	}, agentToken); err == nil {
+		t.Error("RegisterAgent() with agent token error = nil, want error")
+	}
+
+	claims := &atoa.AgentTokenClaims{}
+	if err := atoa.ParseTokenWithPublicKey(agentToken, platform.PublicKey(), claims); err != nil {
+		t.Errorf("agent token does not verify with platform key: %v", err)
+	}
+
+	offers, err := agentClient.ListOffers(context.Background())
+	if err != nil {
+		t.Fatalf("ListOffers() error = %v", err)
+	}
+	if len(offers) != 1 {
+		t.Fatalf("len(offers) = %v, want %v", len(offers), 1)
+	}
+
+	session, err := agentClient.CreateSession(context.Background(), offers[0].Header.ID)
+	if err != nil {
+		t.Fatalf("CreateSession() error = %v", err)
+	}
+
+	sessions, err := agentClient.ListSessions(context.Background(), atoa.SessionListOptions{
+		Status: atoa.SessionStatusActive,
+	})
+	if err != nil {
+		t.Fatalf("ListSessions() error = %v", err)
+	}
+	if len(sessions) != 1 || sessions[0].SessionID != session.SessionID {
+		t.Errorf("ListSessions() = %+v, want [%s]", sessions, session.SessionID)
+	}
+
+	receipt, err := agentClient.SendMessageWithReceipt(context.Background(), atoa.A2AMessage{
+		SessionID:   session.SessionID,
+		FromAgentID: "agent-1",
+		ToAgentID:   "agent-2",
+		Type:        "text",
+		Payload:     json.RawMessage(`{"content": "Hello"}`),
+		Timestamp:   time.Now(),
+	})
+	if err != nil {
+		t.Fatalf("SendMessageWithReceipt() error = %v", err)
+	}
+	if receipt.MessageID == "" {
+		t.Error("SendMessageWithReceipt() returned receipt without message ID")
+	}
+	if got := len(platform.Messages()); got != 1 {
+		t.Errorf("len(Messages()) = %v, want %v", got, 1)
+	}
+
+	// Clients that allow custom message types can send them
+	agentClient.AllowCustomMessageTypes = true
+	err = agentClient.SendMessage(context.Background(), atoa.A2AMessage{
+		SessionID:   session.SessionID,
+		FromAgentID: "agent-1",
+		ToAgentID:   "agent-2",
+		Type:        "x-custom",
+		Payload:     json.RawMessage(`{}`),
+		Timestamp:   time.Now(),
+	})
+	if err != nil {
+		t.Fatalf("SendMessage() with custom type error = %v", err)
+	}
+	if got := len(platform.Messages()); got != 2 {
+		t.Errorf("len(Messages()) = %v, want %v", got, 2)
+	}
+}
+
+func TestFakePlatform_UnverifiedOrg(t *testing.T) {
+	platform := NewFakePlatform()
+	defer platform.Close()
+
+	platform.SetOrgVerified("test-org", false)
+	orgToken, err := atoa.IssueOrgToken("test-org", false, platform.PrivateKey())
+	if err != nil {
+		t.Fatalf("IssueOrgToken() error = %v", err)
+	}
+
+	agentClient := atoa.NewAgentClient(platform.URL())
+	agentToken, err := agentClient.RegisterAgent(&atoa.AgentCard{
+		AgentID:      "agent-1",
+		OrgID:        "test-org",
+		Capabilities: []string{"text"},
+	}, orgToken)
+	if err != nil {
+		t.Fatalf("RegisterAgent() error = %v", err)
+	}
+	agentClient.SetToken(agentToken)
+
+	if _, err := agentClient.ListOffers(context.Background()); err == nil {
+		t.Error("ListOffers() for unverified agent error = nil, want error")
+	}
+}
@@ -1,9 +1,13 @@
 package atoa
 
 import (
+	"crypto"
 	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
 	"errors"
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
@@ -18,8 +22,50 @@ const (
 	AgentTokenAudience = "atoa.session"
 	// DefaultTokenExpiry is the default token expiration time
 	DefaultTokenExpiry = 1 * time.Hour
+	// DefaultLeeway is the clock skew tolerated by the token parsers unless
+	// other VerifyOptions are given
+	DefaultLeeway = 30 * time.Second
 )
 
+// timeNow is the clock used for issuing tokens and checking expiry. Tests
+// replace it through SetTimeFunc.
+var timeNow = time.Now
+
+// SetTimeFunc replaces the clock used for token issuance and expiry checks and
+// returns a function restoring the previous one. It is meant for tests and is
+// not safe to call concurrently with token operations.
+func SetTimeFunc(now func() time.Time) (restore func()) {
+	previous := timeNow
+	timeNow = now
+	return func() { timeNow = previous }
+}
+
+// VerifyOptions tunes how token time claims are checked. Each caller can pass
+// its own options to the ...WithOptions parsers.
+type VerifyOptions struct {
+	// Leeway is the clock skew tolerated when checking exp, iat and nbf
+	Leeway time.Duration
+	// Audiences, when non-empty, makes the parsers accept only tokens whose
+	// aud claim contains at least one of them
+	Audiences []string
+}
+
+// DefaultVerifyOptions returns the options used by the parsers that do not
+// take VerifyOptions
+func DefaultVerifyOptions() VerifyOptions {
+	return VerifyOptions{Leeway: DefaultLeeway}
+}
+
+// parserOptions returns the jwt parser options shared by all token parsers
+func (o VerifyOptions) parserOptions() []jwt.ParserOption {
+	return []jwt.ParserOption{
+		jwt.WithExpirationRequired(),
+		jwt.WithIssuedAt(),
+		jwt.WithTimeFunc(timeNow),
+		jwt.WithLeeway(o.Leeway),
+	}
+}
+
 // OrgTokenClaims represents the claims in an organization JWT token
 type OrgTokenClaims struct {
 	jwt.RegisteredClaims
@@ -36,13 +82,21 @@ type AgentTokenClaims struct {
 	Capabilities []string `json:"capabilities"`
 }
 
-// IssueOrgToken issues a new JWT token for an organization
-func IssueOrgToken(orgID string, verified bool, privateKey *ecdsa.PrivateKey) (string, error) {
-	now := time.Now()
+// IssueOrgToken issues a new JWT token for an organization. The private key
+// must be an *ecdsa.PrivateKey on P-256, P-384 or P-521 (ES256, ES384 or
+// ES512) or an *rsa.PrivateKey (RS256). The token is valid for the given
+// audiences in order, or for OrgTokenAudience if none are given.
+func IssueOrgToken(orgID string, verified bool, privateKey crypto.Signer, audiences ...string) (string, error) {
+	method, err := signingMethodFor(privateKey)
+	if err != nil {
+		return "", err
+	}
+
+	now := timeNow()
 	claims := OrgTokenClaims{
 		RegisteredClaims: jwt.RegisteredClaims{
 			Issuer:    TokenIssuer,
-			Audience:  jwt.ClaimStrings{OrgTokenAudience},
+			Audience:  audienceClaim(audiences, OrgTokenAudience),
 			IssuedAt:  jwt.NewNumericDate(now),
 			ExpiresAt: jwt.NewNumericDate(now.Add(DefaultTokenExpiry)),
 		},
@@ -50,57 +104,127 @@ func IssueOrgToken(orgID string, verified bool, privateKey *ecdsa.PrivateKey) (s
 		Verified: verified,
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodES256, claims)
+	token := jwt.NewWithClaims(method, claims)
 	return token.SignedString(privateKey)
 }
 
-// IssueAgentToken issues a new JWT token for an agent
-func IssueAgentToken(card *AgentCard, orgToken string, privateKey *ecdsa.PrivateKey) (string, error) {
-	// Parse and validate the org token first
-	orgClaims := &OrgTokenClaims{}
-	err := ParseTokenWithPublicKey(orgToken, &privateKey.PublicKey, orgClaims)
+// IssueAgentToken issues a new JWT token for an agent. The private key must be
+// an *ecdsa.PrivateKey on P-256, P-384 or P-521 (ES256, ES384 or ES512) or an
+// *rsa.PrivateKey (RS256). The token is valid for the given audiences in
+// order, or for AgentTokenAudience if none are given. The org token must be
+// valid for OrgTokenAudience.
+func IssueAgentToken(card *AgentCard, orgToken string, privateKey crypto.Signer, audiences ...string) (string, error) {
+	return issueAgentToken(card, orgToken, card.Capabilities, privateKey, audiences)
+}
+
+// IssueScopedAgentToken is like IssueAgentToken but the token carries only the
+// capabilities in scope, which must be a non-empty subset of
+// card.Capabilities
+func IssueScopedAgentToken(card *AgentCard, orgToken string, scope []string, privateKey crypto.Signer, audiences ...string) (string, error) {
+	if len(scope) == 0 {
+		return "", errors.New("scope must name at least one capability")
+	}
+	if missing := missingCapabilities(card.Capabilities, scope, nil); len(missing) > 0 {
+		return "", fmt.Errorf("scope exceeds card capabilities: %s", strings.Join(missing, ", "))
+	}
+	return issueAgentToken(card, orgToken, unionStrings(scope, nil), privateKey, audiences)
+}
+
+// IssueAgentTokens is like IssueAgentToken for many cards of one org. The org
+// token is verified once, then a token is minted per card. The results are
+// index-aligned with cards: each card gets either a token or an error, and a
+// failing card does not stop the others.
+func IssueAgentTokens(cards []*AgentCard, orgToken string, privateKey crypto.Signer, audiences ...string) ([]string, []error) {
+	tokens := make([]string, len(cards))
+	errs := make([]error, len(cards))
+
+	orgClaims, method, err := verifyIssuingOrgToken(orgToken, privateKey)
 	if err != nil {
-		return "", fmt.Errorf("invalid org token: %w", err)
+		for i := range errs {
+			errs[i] = err
+		}
+		return tokens, errs
 	}
 
+	for i, card := range cards {
+		if card == nil {
+			errs[i] = errors.New("agent card is required")
+			continue
+		}
+		tokens[i], errs[i] = mintAgentToken(card, orgClaims, card.Capabilities, method, privateKey, audiences)
+	}
+	return tokens, errs
+}
+
+// issueAgentToken verifies the org token and issues an agent token for the
+// card with the given capabilities
+func issueAgentToken(card *AgentCard, orgToken string, capabilities []string, privateKey crypto.Signer, audiences []string) (string, error) {
+	orgClaims, method, err := verifyIssuingOrgToken(orgToken, privateKey)
+	if err != nil {
+		return "", err
+	}
+	return mintAgentToken(card, orgClaims, capabilities, method, privateKey, audiences)
+}
+
+// verifyIssuingOrgToken checks that the org token was signed with
+// privateKey for OrgTokenAudience, so that an agent token cannot stand in for
+// it, and returns its claims and the signing method for agent tokens
+func verifyIssuingOrgToken(orgToken string, privateKey crypto.Signer) (*OrgTokenClaims, jwt.SigningMethod, error) {
+	method, err := signingMethodFor(privateKey)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	opts := DefaultVerifyOptions()
+	opts.Audiences = []string{OrgTokenAudience}
+	orgClaims := &OrgTokenClaims{}
+	if err := ParseTokenWithOptions(orgToken, privateKey.Public(), orgClaims, opts); err != nil {
+		return nil, nil, fmt.Errorf("invalid org token: %w", err)
+	}
+	return orgClaims, method, nil
+}
+
+// mintAgentToken signs an agent token for a card of the org in orgClaims
+func mintAgentToken(card *AgentCard, orgClaims *OrgTokenClaims, capabilities []string, method jwt.SigningMethod, privateKey crypto.Signer, audiences []string) (string, error) {
 	// Verify org_id matches
 	if orgClaims.OrgID != card.OrgID {
 		return "", errors.New("org_id mismatch between card and token")
 	}
 
 	// Create agent token claims
-	now := time.Now()
+	now := timeNow()
 	claims := AgentTokenClaims{
 		RegisteredClaims: jwt.RegisteredClaims{
 			Issuer:    TokenIssuer,
-			Audience:  jwt.ClaimStrings{AgentTokenAudience},
+			Audience:  audienceClaim(audiences, AgentTokenAudience),
 			IssuedAt:  jwt.NewNumericDate(now),
 			ExpiresAt: jwt.NewNumericDate(now.Add(DefaultTokenExpiry)),
 		},
 		AgentID:      card.AgentID,
 		OrgID:        card.OrgID,
 		Verified:     orgClaims.Verified, // Inherit verification status from org
-		Capabilities: card.Capabilities,
+		Capabilities: capabilities,
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodES256, claims)
+	token := jwt.NewWithClaims(method, claims)
 	return token.SignedString(privateKey)
 }
 
-// ParseOrgToken parses and validates an organization JWT token
+// ParseOrgToken parses and validates an organization JWT token. It has no
+// way to look up the signing key yet and so rejects every token; use
+// ParseOrgTokenWithOptions with the issuer's public key.
 func ParseOrgToken(tokenString string) (*OrgTokenClaims, error) {
 	// First parse without verification to get the public key
-	_, _, err := jwt.NewParser().ParseUnverified(tokenString, &OrgTokenClaims{})
-	if err != nil {
-		return nil, fmt.Errorf("failed to parse token: %w", err)
+	if _, err := PeekOrgClaims(tokenString); err != nil {
+		return nil, err
 	}
 
 	// TODO: Get the public key from a trusted source using keyID from token.Header["kid"]
 	// For now, we'll just parse the claims without verification
-	parser := jwt.NewParser(jwt.WithExpirationRequired(), jwt.WithIssuedAt())
+	parser := jwt.NewParser(DefaultVerifyOptions().parserOptions()...)
 	token, err := parser.ParseWithClaims(tokenString, &OrgTokenClaims{}, func(token *jwt.Token) (interface{}, error) {
-		if _, ok := token.Method.(*jwt.SigningMethodECDSA); !ok {
-			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+		if err := checkSigningMethod(token); err != nil {
+			return nil, err
 		}
 		// For testing purposes, we'll skip verification
 		// In production, we would get the public key from a trusted source using keyID
@@ -119,20 +243,32 @@ func ParseOrgToken(tokenString string) (*OrgTokenClaims, error) {
 	return claims, nil
 }
 
-// ParseAgentTokenClaims parses and validates an agent JWT token
+// ParseOrgTokenWithOptions verifies an organization JWT token with publicKey,
+// an *ecdsa.PublicKey or an *rsa.PublicKey, and returns its claims. Time
+// claims and audiences are checked as opts says.
+func ParseOrgTokenWithOptions(tokenString string, publicKey crypto.PublicKey, opts VerifyOptions) (*OrgTokenClaims, error) {
+	claims := &OrgTokenClaims{}
+	if err := ParseTokenWithOptions(tokenString, publicKey, claims, opts); err != nil {
+		return nil, fmt.Errorf("failed to parse token: %w", err)
+	}
+	return claims, nil
+}
+
+// ParseAgentTokenClaims parses and validates an agent JWT token. It has no
+// way to look up the signing key yet and so rejects every token; use
+// ParseAgentTokenClaimsWithOptions with the issuer's public key.
 func ParseAgentTokenClaims(tokenString string) (*AgentTokenClaims, error) {
 	// First parse without verification to get the public key
-	_, _, err := jwt.NewParser().ParseUnverified(tokenString, &AgentTokenClaims{})
-	if err != nil {
-		return nil, fmt.Errorf("failed to parse token: %w", err)
+	if _, err := PeekAgentClaims(tokenString); err != nil {
+		return nil, err
 	}
 
 	// TODO: Get the public key from a trusted source using keyID from token.Header["kid"]
 	// For now, we'll just parse the claims without verification
-	parser := jwt.NewParser(jwt.WithExpirationRequired(), jwt.WithIssuedAt())
+	parser := jwt.NewParser(DefaultVerifyOptions().parserOptions()...)
 	token, err := parser.ParseWithClaims(tokenString, &AgentTokenClaims{}, func(token *jwt.Token) (interface{}, error) {
-		if _, ok := token.Method.(*jwt.SigningMethodECDSA); !ok {
-			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+		if err := checkSigningMethod(token); err != nil {
+			return nil, err
 		}
 		// For testing purposes, we'll skip verification
 		// In production, we would get the public key from a trusted source using keyID
@@ -151,14 +287,138 @@ func ParseAgentTokenClaims(tokenString string) (*AgentTokenClaims, error) {
 	return claims, nil
 }
 
-// ParseTokenWithPublicKey parses and validates a JWT token with a specific public key
-func ParseTokenWithPublicKey(tokenString string, publicKey *ecdsa.PublicKey, claims jwt.Claims) error {
-	parser := jwt.NewParser(jwt.WithExpirationRequired(), jwt.WithIssuedAt())
-	_, err := parser.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
-		if _, ok := token.Method.(*jwt.SigningMethodECDSA); !ok {
-			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+// ParseAgentTokenClaimsWithOptions verifies an agent JWT token with
+// publicKey, an *ecdsa.PublicKey or an *rsa.PublicKey, and returns its
+// claims. Time claims and audiences are checked as opts says.
+func ParseAgentTokenClaimsWithOptions(tokenString string, publicKey crypto.PublicKey, opts VerifyOptions) (*AgentTokenClaims, error) {
+	claims := &AgentTokenClaims{}
+	if err := ParseTokenWithOptions(tokenString, publicKey, claims, opts); err != nil {
+		return nil, fmt.Errorf("failed to parse token: %w", err)
+	}
+	return claims, nil
+}
+
+// PeekOrgClaims parses an organization JWT token WITHOUT verifying its signature
+// or expiry. The returned claims are untrusted and must only be used to select
+// the key needed for a verified parse (e.g. by looking up org_id).
+func PeekOrgClaims(tokenString string) (*OrgTokenClaims, error) {
+	claims := &OrgTokenClaims{}
+	if _, _, err := jwt.NewParser().ParseUnverified(tokenString, claims); err != nil {
+		return nil, fmt.Errorf("failed to parse token: %w", err)
+	}
+	return claims, nil
+}
+
+// PeekAgentClaims parses an agent JWT token WITHOUT verifying its signature
+// or expiry. The returned claims are untrusted and must only be used to select
+// the key needed for a verified parse (e.g. by looking up agent_id or org_id).
+func PeekAgentClaims(tokenString string) (*AgentTokenClaims, error) {
+	claims := &AgentTokenClaims{}
+	if _, _, err := jwt.NewParser().ParseUnverified(tokenString, claims); err != nil {
+		return nil, fmt.Errorf("failed to parse token: %w", err)
+	}
+	return claims, nil
+}
+
+// ParseTokenWithPublicKey parses and validates a JWT token with a specific
+// public key, which must be an *ecdsa.PublicKey or an *rsa.PublicKey
+func ParseTokenWithPublicKey(tokenString string, publicKey crypto.PublicKey, claims jwt.Claims) error {
+	return ParseTokenWithOptions(tokenString, publicKey, claims, DefaultVerifyOptions())
+}
+
+// ParseTokenWithOptions is like ParseTokenWithPublicKey with tunable time checks
+func ParseTokenWithOptions(tokenString string, publicKey crypto.PublicKey, claims jwt.Claims, opts VerifyOptions) error {
+	parser := jwt.NewParser(opts.parserOptions()...)
+	if _, err := parser.ParseWithClaims(tokenString, claims, publicKeyFunc(publicKey)); err != nil {
+		return err
+	}
+	return opts.checkAudience(claims)
+}
+
+// checkAudience returns jwt.ErrTokenInvalidAudience unless the claims contain
+// one of o.Audiences. Any audience is accepted when o.Audiences is empty.
+func (o VerifyOptions) checkAudience(claims jwt.Claims) error {
+	if len(o.Audiences) == 0 {
+		return nil
+	}
+
+	audiences, err := claims.GetAudience()
+	if err != nil {
+		return err
+	}
+	for _, want := range o.Audiences {
+		for _, got := range audiences {
+			if got == want {
+				return nil
+			}
 		}
-		return publicKey, nil
-	})
-	return err
+	}
+	return jwt.ErrTokenInvalidAudience
+}
+
+// audienceClaim returns the aud claim for the given audiences, falling back to
+// defaultAudience when there are none
+func audienceClaim(audiences []string, defaultAudience string) jwt.ClaimStrings {
+	if len(audiences) == 0 {
+		return jwt.ClaimStrings{defaultAudience}
+	}
+	return append(jwt.ClaimStrings(nil), audiences...)
+}
+
+// signingMethodFor returns the JWT signing method for the private key type.
+// ECDSA keys use the method matching their curve.
+func signingMethodFor(privateKey crypto.Signer) (jwt.SigningMethod, error) {
+	switch key := privateKey.(type) {
+	case *ecdsa.PrivateKey:
+		switch key.Curve {
+		case elliptic.P256():
+			return jwt.SigningMethodES256, nil
+		case elliptic.P384():
+			return jwt.SigningMethodES384, nil
+		case elliptic.P521():
+			return jwt.SigningMethodES512, nil
+		default:
+			return nil, fmt.Errorf("unsupported ECDSA curve %s", key.Curve.Params().Name)
+		}
+	case *rsa.PrivateKey:
+		return jwt.SigningMethodRS256, nil
+	default:
+		return nil, fmt.Errorf("unsupported private key type %T", privateKey)
+	}
+}
+
+// checkSigningMethod rejects tokens not signed with a supported algorithm
+func checkSigningMethod(token *jwt.Token) error {
+	switch token.Method.(type) {
+	case *jwt.SigningMethodECDSA, *jwt.SigningMethodRSA:
+		return nil
+	default:
+		return fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+	}
+}
+
+// publicKeyFunc returns a jwt.Keyfunc that verifies with publicKey, requiring
+// the token's alg header to match the key type
+func publicKeyFunc(publicKey crypto.PublicKey) jwt.Keyfunc {
+	return func(token *jwt.Token) (interface{}, error) {
+		switch key := publicKey.(type) {
+		case *ecdsa.PublicKey:
+			if key == nil {
+				return nil, errors.New("public key is required")
+			}
+			if _, ok := token.Method.(*jwt.SigningMethodECDSA); ok {
+				return key, nil
+			}
+		case *rsa.PublicKey:
+			if key == nil {
+				return nil, errors.New("public key is required")
+			}
+			if _, ok := token.Method.(*jwt.SigningMethodRSA); ok {
+				return key, nil
+			}
+		default:
+			return nil, fmt.Errorf("unsupported public key type %T", publicKey)
+		}
+		return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+	}
 }
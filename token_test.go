@@ -4,6 +4,8 @@ import (
 	"crypto/ecdsa"
 	"crypto/elliptic"
 	"crypto/rand"
+	"crypto/rsa"
+	"errors"
 	"testing"
 	"time"
 
@@ -118,6 +120,33 @@ func TestIssueAgentToken_OrgMismatch(t *testing.T) {
 	}
 }
 
+func TestIssueAgentToken_AgentTokenAsOrgToken(t *testing.T) {
+	privateKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate private key: %v", err)
+	}
+
+	orgToken, err := IssueOrgToken("test-org", true, privateKey)
+	if err != nil {
+		t.Fatalf("failed to issue org token: %v", err)
+	}
+	card := &AgentCard{
+		AgentID:      "test-agent",
+		OrgID:        "test-org",
+		Capabilities: []string{"text"},
+	}
+	agentToken, err := IssueAgentToken(card, orgToken, privateKey)
+	if err != nil {
+		t.Fatalf("IssueAgentToken() error = %v", err)
+	}
+
+	// An agent token signed with the same key lacks the org audience
+	_, err = IssueAgentToken(card, agentToken, privateKey)
+	if !errors.Is(err, jwt.ErrTokenInvalidAudience) {
+		t.Errorf("IssueAgentToken() with agent token error = %v, want %v", err, jwt.ErrTokenInvalidAudience)
+	}
+}
+
 func TestIssueAgentToken_ExpiredOrgToken(t *testing.T) {
 	privateKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
 	if err != nil {
@@ -155,3 +184,412 @@ func TestIssueAgentToken_ExpiredOrgToken(t *testing.T) {
 		t.Error("IssueAgentToken() error = nil, want error")
 	}
 }
+
+func TestPeekClaims(t *testing.T) {
+	privateKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate private key: %v", err)
+	}
+
+	orgToken, err := IssueOrgToken("test-org", true, privateKey)
+	if err != nil {
+		t.Fatalf("failed to issue org token: %v", err)
+	}
+
+	orgClaims, err := PeekOrgClaims(orgToken)
+	if err != nil {
+		t.Fatalf("PeekOrgClaims() error = %v", err)
+	}
+	if orgClaims.OrgID != "test-org" {
+		t.Errorf("orgClaims.OrgID = %v, want %v", orgClaims.OrgID, "test-org")
+	}
+
+	card := &AgentCard{
+		AgentID:      "test-agent",
+		OrgID:        "test-org",
+		Capabilities: []string{"text"},
+	}
+	agentToken, err := IssueAgentToken(card, orgToken, privateKey)
+	if err != nil {
+		t.Fatalf("failed to issue agent token: %v", err)
+	}
+
+	agentClaims, err := PeekAgentClaims(agentToken)
+	if err != nil {
+		t.Fatalf("PeekAgentClaims() error = %v", err)
+	}
+	if agentClaims.AgentID != "test-agent" {
+		t.Errorf("agentClaims.AgentID = %v, want %v", agentClaims.AgentID, "test-agent")
+	}
+
+	if _, err := PeekAgentClaims("not-a-token"); err == nil {
+		t.Error("PeekAgentClaims() error = nil, want error")
+	}
+}
+
+func TestIssueAgentToken_RSA(t *testing.T) {
+	privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("failed to generate private key: %v", err)
+	}
+
+	orgToken, err := IssueOrgToken("test-org", true, privateKey)
+	if err != nil {
+		t.Fatalf("IssueOrgToken() error = %v", err)
+	}
+
+	card := &AgentCard{
+		AgentID:      "test-agent",
+		OrgID:        "test-org",
+		Capabilities: []string{"text"},
+	}
+	token, err := IssueAgentToken(card, orgToken, privateKey)
+	if err != nil {
+		t.Fatalf("IssueAgentToken() error = %v", err)
+	}
+
+	claims := &AgentTokenClaims{}
+	if err := ParseTokenWithPublicKey(token, &privateKey.PublicKey, claims); err != nil {
+		t.Errorf("ParseTokenWithPublicKey() error = %v", err)
+	}
+	if claims.AgentID != "test-agent" {
+		t.Errorf("claims.AgentID = %v, want %v", claims.AgentID, "test-agent")
+	}
+
+	// An RS256 token must not verify against an ECDSA key
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate private key: %v", err)
+	}
+	if err := ParseTokenWithPublicKey(token, &ecKey.PublicKey, &AgentTokenClaims{}); err == nil {
+		t.Error("ParseTokenWithPublicKey() with mismatched key type error = nil, want error")
+	}
+}
+
+func TestParseTokenWithOptions_Leeway(t *testing.T) {
+	privateKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate private key: %v", err)
+	}
+
+	// Issue a token that expired one minute ago
+	issuedAt := time.Now().Add(-DefaultTokenExpiry - time.Minute)
+	restore := SetTimeFunc(func() time.Time { return issuedAt })
+	token, err := IssueOrgToken("test-org", true, privateKey)
+	restore()
+	if err != nil {
+		t.Fatalf("IssueOrgToken() error = %v", err)
+	}
+
+	if err := ParseTokenWithPublicKey(token, &privateKey.PublicKey, &OrgTokenClaims{}); err == nil {
+		t.Error("ParseTokenWithPublicKey() with expired token error = nil, want error")
+	}
+
+	opts := VerifyOptions{Leeway: 2 * time.Minute}
+	if err := ParseTokenWithOptions(token, &privateKey.PublicKey, &OrgTokenClaims{}, opts); err != nil {
+		t.Errorf("ParseTokenWithOptions() with leeway error = %v", err)
+	}
+}
+
+func TestParseTokenWithPublicKey_DefaultLeeway(t *testing.T) {
+	privateKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate private key: %v", err)
+	}
+
+	// Issue a token that expired ten seconds ago, well within DefaultLeeway
+	issuedAt := time.Now().Add(-DefaultTokenExpiry - 10*time.Second)
+	restore := SetTimeFunc(func() time.Time { return issuedAt })
+	token, err := IssueOrgToken("test-org", true, privateKey)
+	restore()
+	if err != nil {
+		t.Fatalf("IssueOrgToken() error = %v", err)
+	}
+
+	if err := ParseTokenWithPublicKey(token, &privateKey.PublicKey, &OrgTokenClaims{}); err != nil {
+		t.Errorf("ParseTokenWithPublicKey() error = %v, want nil within default leeway", err)
+	}
+
+	strict := VerifyOptions{Leeway: 0}
+	if err := ParseTokenWithOptions(token, &privateKey.PublicKey, &OrgTokenClaims{}, strict); err == nil {
+		t.Error("ParseTokenWithOptions() without leeway error = nil, want error")
+	}
+}
+
+func TestParseTokenClaimsWithOptions(t *testing.T) {
+	privateKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate private key: %v", err)
+	}
+
+	// Issue tokens that expired ten seconds ago, within DefaultLeeway
+	issuedAt := time.Now().Add(-DefaultTokenExpiry - 10*time.Second)
+	restore := SetTimeFunc(func() time.Time { return issuedAt })
+	orgToken, err := IssueOrgToken("test-org", true, privateKey)
+	if err != nil {
+		restore()
+		t.Fatalf("IssueOrgToken() error = %v", err)
+	}
+	card := &AgentCard{AgentID: "agent-1", OrgID: "test-org", Capabilities: []string{"text"}}
+	agentToken, err := IssueAgentToken(card, orgToken, privateKey)
+	restore()
+	if err != nil {
+		t.Fatalf("IssueAgentToken() error = %v", err)
+	}
+
+	parseOrg := func(opts VerifyOptions) (string, error) {
+		claims, err := ParseOrgTokenWithOptions(orgToken, &privateKey.PublicKey, opts)
+		if err != nil {
+			return "", err
+		}
+		return claims.OrgID, nil
+	}
+	parseAgent := func(opts VerifyOptions) (string, error) {
+		claims, err := ParseAgentTokenClaimsWithOptions(agentToken, &privateKey.PublicKey, opts)
+		if err != nil {
+			return "", err
+		}
+		return claims.AgentID, nil
+	}
+
+	tests := []struct {
+		name    string
+		parse   func(VerifyOptions) (string, error)
+		opts    VerifyOptions
+		want    string
+		wantErr bool
+	}{
+		{"org default leeway", parseOrg, DefaultVerifyOptions(), "test-org", false},
+		{"org no leeway", parseOrg, VerifyOptions{}, "", true},
+		{"org audience", parseOrg, VerifyOptions{Leeway: DefaultLeeway, Audiences: []string{OrgTokenAudience}}, "test-org", false},
+		{"org wrong audience", parseOrg, VerifyOptions{Leeway: DefaultLeeway, Audiences: []string{AgentTokenAudience}}, "", true},
+		{"agent default leeway", parseAgent, DefaultVerifyOptions(), "agent-1", false},
+		{"agent no leeway", parseAgent, VerifyOptions{}, "", true},
+		{"agent audience", parseAgent, VerifyOptions{Leeway: DefaultLeeway, Audiences: []string{AgentTokenAudience}}, "agent-1", false},
+		{"agent wrong audience", parseAgent, VerifyOptions{Leeway: DefaultLeeway, Audiences: []string{OrgTokenAudience}}, "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.parse(tt.opts)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parse error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("parsed ID = %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	otherKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate private key: %v", err)
+	}
+	if _, err := ParseOrgTokenWithOptions(orgToken, &otherKey.PublicKey, DefaultVerifyOptions()); err == nil {
+		t.Error("ParseOrgTokenWithOptions() with another key error = nil, want error")
+	}
+}
+
+func TestIssueOrgToken_MultipleAudiences(t *testing.T) {
+	privateKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate private key: %v", err)
+	}
+
+	token, err := IssueOrgToken("test-org", true, privateKey, OrgTokenAudience, "atoa.billing")
+	if err != nil {
+		t.Fatalf("IssueOrgToken() error = %v", err)
+	}
+
+	claims := &OrgTokenClaims{}
+	if err := ParseTokenWithPublicKey(token, &privateKey.PublicKey, claims); err != nil {
+		t.Fatalf("ParseTokenWithPublicKey() error = %v", err)
+	}
+	if len(claims.Audience) != 2 || claims.Audience[0] != OrgTokenAudience || claims.Audience[1] != "atoa.billing" {
+		t.Errorf("claims.Audience = %v, want [%v atoa.billing]", claims.Audience, OrgTokenAudience)
+	}
+
+	tests := []struct {
+		name      string
+		audiences []string
+		wantErr   bool
+	}{
+		{name: "no required audience", audiences: nil, wantErr: false},
+		{name: "first audience", audiences: []string{OrgTokenAudience}, wantErr: false},
+		{name: "second audience", audiences: []string{"atoa.billing"}, wantErr: false},
+		{name: "any of several", audiences: []string{"atoa.other", "atoa.billing"}, wantErr: false},
+		{name: "no match", audiences: []string{AgentTokenAudience}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := DefaultVerifyOptions()
+			opts.Audiences = tt.audiences
+			err := ParseTokenWithOptions(token, &privateKey.PublicKey, &OrgTokenClaims{}, opts)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ParseTokenWithOptions() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr && !errors.Is(err, jwt.ErrTokenInvalidAudience) {
+				t.Errorf("ParseTokenWithOptions() error = %v, want %v", err, jwt.ErrTokenInvalidAudience)
+			}
+		})
+	}
+}
+
+func TestIssueAgentToken_ECDSACurves(t *testing.T) {
+	tests := []struct {
+		name    string
+		curve   elliptic.Curve
+		wantAlg string
+	}{
+		{name: "P-256", curve: elliptic.P256(), wantAlg: "ES256"},
+		{name: "P-384", curve: elliptic.P384(), wantAlg: "ES384"},
+		{name: "P-521", curve: elliptic.P521(), wantAlg: "ES512"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			privateKey, err := ecdsa.GenerateKey(tt.curve, rand.Reader)
+			if err != nil {
+				t.Fatalf("failed to generate private key: %v", err)
+			}
+
+			orgToken, err := IssueOrgToken("test-org", true, privateKey)
+			if err != nil {
+				t.Fatalf("IssueOrgToken() error = %v", err)
+			}
+			token, err := IssueAgentToken(&AgentCard{
+				AgentID:      "test-agent",
+				OrgID:        "test-org",
+				Capabilities: []string{"text"},
+			}, orgToken, privateKey)
+			if err != nil {
+				t.Fatalf("IssueAgentToken() error = %v", err)
+			}
+
+			parsed, _, err := jwt.NewParser().ParseUnverified(token, &AgentTokenClaims{})
+			if err != nil {
+				t.Fatalf("ParseUnverified() error = %v", err)
+			}
+			if parsed.Method.Alg() != tt.wantAlg {
+				t.Errorf("alg = %v, want %v", parsed.Method.Alg(), tt.wantAlg)
+			}
+
+			claims := &AgentTokenClaims{}
+			if err := ParseTokenWithPublicKey(token, &privateKey.PublicKey, claims); err != nil {
+				t.Errorf("ParseTokenWithPublicKey() error = %v", err)
+			}
+			if claims.AgentID != "test-agent" {
+				t.Errorf("claims.AgentID = %v, want %v", claims.AgentID, "test-agent")
+			}
+		})
+	}
+}
+
+func TestIssueScopedAgentToken(t *testing.T) {
+	orgToken, err := IssueOrgToken("test-org", true, testPrivateKey)
+	if err != nil {
+		t.Fatalf("failed to issue org token: %v", err)
+	}
+	card := &AgentCard{
+		AgentID:      "agent-1",
+		OrgID:        "test-org",
+		Capabilities: []string{"text", "file", "image"},
+	}
+
+	tests := []struct {
+		name    string
+		scope   []string
+		want    []string
+		wantErr bool
+	}{
+		{name: "subset", scope: []string{"file", "text"}, want: []string{"file", "text"}},
+		{name: "duplicates dropped", scope: []string{"text", "text"}, want: []string{"text"}},
+		{name: "capability not on card", scope: []string{"text", "audio"}, wantErr: true},
+		{name: "empty scope", scope: nil, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			token, err := IssueScopedAgentToken(card, orgToken, tt.scope, testPrivateKey)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("IssueScopedAgentToken() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+
+			claims := &AgentTokenClaims{}
+			if err := ParseTokenWithPublicKey(token, &testPrivateKey.PublicKey, claims); err != nil {
+				t.Fatalf("ParseTokenWithPublicKey() error = %v", err)
+			}
+			if len(claims.Capabilities) != len(tt.want) {
+				t.Fatalf("Capabilities = %v, want %v", claims.Capabilities, tt.want)
+			}
+			for i := range tt.want {
+				if claims.Capabilities[i] != tt.want[i] {
+					t.Errorf("Capabilities = %v, want %v", claims.Capabilities, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestIssueAgentTokens(t *testing.T) {
+	orgToken, err := IssueOrgToken("test-org", true, testPrivateKey)
+	if err != nil {
+		t.Fatalf("failed to issue org token: %v", err)
+	}
+	cards := []*AgentCard{
+		{AgentID: "agent-1", OrgID: "test-org", Capabilities: []string{"text"}},
+		{AgentID: "agent-2", OrgID: "other-org", Capabilities: []string{"text"}},
+		nil,
+		{AgentID: "agent-4", OrgID: "test-org", Capabilities: []string{"file"}},
+	}
+
+	tokens, errs := IssueAgentTokens(cards, orgToken, testPrivateKey)
+	if len(tokens) != len(cards) || len(errs) != len(cards) {
+		t.Fatalf("got %d tokens and %d errors, want %d of each", len(tokens), len(errs), len(cards))
+	}
+
+	for i, wantErr := range []bool{false, true, true, false} {
+		if (errs[i] != nil) != wantErr {
+			t.Errorf("card %d: error = %v, wantErr %v", i, errs[i], wantErr)
+			continue
+		}
+		if wantErr {
+			if tokens[i] != "" {
+				t.Errorf("card %d: token = %q, want empty", i, tokens[i])
+			}
+			continue
+		}
+
+		claims := &AgentTokenClaims{}
+		if err := ParseTokenWithPublicKey(tokens[i], &testPrivateKey.PublicKey, claims); err != nil {
+			t.Fatalf("card %d: ParseTokenWithPublicKey() error = %v", i, err)
+		}
+		if claims.AgentID != cards[i].AgentID {
+			t.Errorf("card %d: AgentID = %q, want %q", i, claims.AgentID, cards[i].AgentID)
+		}
+		if !claims.Verified {
+			t.Errorf("card %d: Verified = false, want inherited true", i)
+		}
+	}
+}
+
+func TestIssueAgentTokens_InvalidOrgToken(t *testing.T) {
+	cards := []*AgentCard{
+		{AgentID: "agent-1", OrgID: "test-org"},
+		{AgentID: "agent-2", OrgID: "test-org"},
+	}
+
+	tokens, errs := IssueAgentTokens(cards, "not-a-token", testPrivateKey)
+	for i := range cards {
+		if errs[i] == nil {
+			t.Errorf("card %d: expected error for invalid org token", i)
+		}
+		if tokens[i] != "" {
+			t.Errorf("card %d: token = %q, want empty", i, tokens[i])
+		}
+	}
+}